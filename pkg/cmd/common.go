@@ -12,9 +12,11 @@ import (
 	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
 )
 
-func GetResourceRequestRecommendationsByPod(pod corev1.Pod, recommendMap map[string]analysisv1alph1.Recommendation) *analysisv1alph1.ResourceRequestRecommendation {
-	for _, ref := range pod.OwnerReferences {
-		key := GetOwnerKey(analysisv1alph1.ResourceRecommender, ref, pod.Namespace)
+// GetResourceRequestRecommendationsByOwners returns the resource recommendation of the first owner having one,
+// the owners of a pod are its workloads, e.g. the Deployment instead of the ReplicaSet.
+func GetResourceRequestRecommendationsByOwners(owners []metav1.OwnerReference, namespace string, recommendMap map[string]analysisv1alph1.Recommendation) *analysisv1alph1.ResourceRequestRecommendation {
+	for _, ref := range owners {
+		key := GetOwnerKey(analysisv1alph1.ResourceRecommender, ref, namespace)
 		if recommend, exist := recommendMap[key]; exist {
 			if recommend.Status.RecommendedValue == "" {
 				continue
@@ -84,3 +86,53 @@ func PrintReplicas(replicas int32) string {
 
 	return strconv.Itoa(int(replicas))
 }
+
+// ContainerRequests returns the cpu and memory requests of the container, zero if not set
+func ContainerRequests(container corev1.Container) (*resource.Quantity, *resource.Quantity) {
+	cpu := resource.NewQuantity(0, resource.DecimalSI)
+	memory := resource.NewQuantity(0, resource.BinarySI)
+	if requestCpu, exist := container.Resources.Requests[corev1.ResourceCPU]; exist && !requestCpu.IsZero() {
+		cpu = &requestCpu
+	}
+	if requestMemory, exist := container.Resources.Requests[corev1.ResourceMemory]; exist && !requestMemory.IsZero() {
+		memory = &requestMemory
+	}
+
+	return cpu, memory
+}
+
+// RecommendedContainerRequests returns the recommended cpu and memory requests of the container, zero if not recommended
+func RecommendedContainerRequests(containerName string, recommendation *analysisv1alph1.ResourceRequestRecommendation) (*resource.Quantity, *resource.Quantity) {
+	cpu := resource.NewQuantity(0, resource.DecimalSI)
+	memory := resource.NewQuantity(0, resource.BinarySI)
+	if recommendation == nil {
+		return cpu, memory
+	}
+
+	for _, recContainer := range recommendation.Containers {
+		if recContainer.ContainerName != containerName {
+			continue
+		}
+		if recCpu, err := resource.ParseQuantity(recContainer.Target[corev1.ResourceCPU]); err == nil {
+			cpu = &recCpu
+		}
+		if recMemory, err := resource.ParseQuantity(recContainer.Target[corev1.ResourceMemory]); err == nil {
+			memory = &recMemory
+		}
+	}
+
+	return cpu, memory
+}
+
+// QuantityDiff returns the current minus the recommended quantity without changing either,
+// the diff is zero when there is no recommended value.
+func QuantityDiff(current, recommended *resource.Quantity) *resource.Quantity {
+	diff := resource.NewQuantity(0, current.Format)
+	if recommended.IsZero() {
+		return diff
+	}
+
+	diff.Add(*current)
+	diff.Sub(*recommended)
+	return diff
+}
@@ -0,0 +1,541 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	costExample = `
+# estimate the monthly cost of the workloads in kube-system namespace before and after adopting the recommendations
+%[1]s cost -n kube-system
+
+# estimate the monthly cost per namespace of the whole cluster
+%[1]s cost -A --group-by namespace
+
+# estimate the monthly cost per recommendation rule with your own prices
+%[1]s cost -A --group-by rule --cpu-price 0.04 --memory-price 0.005
+
+# price the workloads by the type of the nodes they run on
+%[1]s cost -A --node-prices node-prices.yaml
+
+# estimate the cost from a snapshot saved by 'kubectl crane snapshot'
+%[1]s cost -A --from-file snapshot.yaml
+`
+)
+
+const (
+	CostGroupByWorkload  = "workload"
+	CostGroupByNamespace = "namespace"
+	CostGroupByRule      = "rule"
+)
+
+// WorkloadCost is the monthly cost of the requests of a workload before and after
+// adopting its Resource and Replicas recommendations.
+type WorkloadCost struct {
+	TargetRef corev1.ObjectReference
+	Rule      string
+	NodeType  string
+	// the labels of the workload, empty if it can't be read from the cluster
+	Labels map[string]string
+
+	Replicas            int32
+	RecommendedReplicas int32
+
+	// the requests of the containers of one replica read from the workload, and with the Resource recommendation adopted
+	Containers            []utils.ContainerRequest
+	RecommendedContainers []utils.ContainerRequest
+
+	// the requests of all the replicas
+	Cpu               resource.Quantity
+	Memory            resource.Quantity
+	RecommendedCpu    resource.Quantity
+	RecommendedMemory resource.Quantity
+
+	Cost            float64
+	RecommendedCost float64
+}
+
+func (c *WorkloadCost) Savings() float64 {
+	return c.Cost - c.RecommendedCost
+}
+
+// CostGroup is the sum of the costs of the workloads in a namespace or a recommendation rule
+type CostGroup struct {
+	Name      string
+	Workloads int
+
+	Cost            float64
+	RecommendedCost float64
+}
+
+func (g *CostGroup) Savings() float64 {
+	return g.Cost - g.RecommendedCost
+}
+
+type CostOptions struct {
+	CommonOptions *options.CommonOptions
+	FilterOptions *recommend.RecommendFilterOptions
+
+	CpuPrice       float64
+	MemoryPrice    float64
+	NodePricesFile string
+	NodeTypeLabel  string
+	GroupBy        string
+
+	priceModel *utils.PriceModel
+}
+
+func NewCostOptions() *CostOptions {
+	return &CostOptions{
+		CommonOptions: options.NewCommonOptions(),
+		FilterOptions: recommend.NewRecommendFilterOptions(),
+	}
+}
+
+func NewCmdCost() *cobra.Command {
+	o := NewCostOptions()
+
+	command := &cobra.Command{
+		Use:     "cost",
+		Short:   "Estimate the cost saved by adopting the recommendations",
+		Example: fmt.Sprintf(costExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+costExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.AddFlags(command)
+	o.CommonOptions.AddCommonFlag(command)
+	o.CommonOptions.AddFromFileFlag(command)
+
+	return command
+}
+
+func (o *CostOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if o.CpuPrice < 0 || o.MemoryPrice < 0 {
+		return fmt.Errorf("the prices should not be negative")
+	}
+
+	switch o.GroupBy {
+	case CostGroupByWorkload, CostGroupByNamespace, CostGroupByRule:
+	default:
+		return fmt.Errorf("unsupported group %s, please specify one of %s, %s, %s", o.GroupBy, CostGroupByWorkload, CostGroupByNamespace, CostGroupByRule)
+	}
+
+	return nil
+}
+
+func (o *CostOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	o.priceModel = &utils.PriceModel{
+		Default: utils.ResourcePrice{
+			Cpu:    o.CpuPrice,
+			Memory: o.MemoryPrice,
+		},
+	}
+	if len(o.NodePricesFile) > 0 {
+		nodeTypes, err := utils.LoadNodeTypePrices(o.NodePricesFile)
+		if err != nil {
+			return err
+		}
+		o.priceModel.NodeTypes = nodeTypes
+	}
+
+	return nil
+}
+
+func (o *CostOptions) Run() error {
+	recommendations, err := o.FilterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return err
+	}
+
+	costs, err := NewCostEstimator(o.CommonOptions, o.priceModel, o.NodeTypeLabel).Estimate(recommendations)
+	if err != nil {
+		return err
+	}
+
+	switch o.GroupBy {
+	case CostGroupByNamespace:
+		renderCostGroups("NAMESPACE", GroupWorkloadCosts(costs, func(cost *WorkloadCost) string {
+			return cost.TargetRef.Namespace
+		}), o)
+	case CostGroupByRule:
+		renderCostGroups("RULE", GroupWorkloadCosts(costs, func(cost *WorkloadCost) string {
+			return cost.Rule
+		}), o)
+	default:
+		renderWorkloadCosts(costs, o)
+	}
+
+	return nil
+}
+
+// CostEstimator prices the workloads targeted by recommendations, the workloads are read
+// from the cluster to get the replicas, the current requests and the node type.
+type CostEstimator struct {
+	commonOptions *options.CommonOptions
+	priceModel    *utils.PriceModel
+	nodeTypeLabel string
+
+	gvrs      map[string]*schema.GroupVersionResource
+	nodeTypes map[string]string
+}
+
+func NewCostEstimator(commonOptions *options.CommonOptions, priceModel *utils.PriceModel, nodeTypeLabel string) *CostEstimator {
+	return &CostEstimator{
+		commonOptions: commonOptions,
+		priceModel:    priceModel,
+		nodeTypeLabel: nodeTypeLabel,
+		gvrs:          map[string]*schema.GroupVersionResource{},
+	}
+}
+
+// Estimate returns the cost of each workload targeted by the Resource or Replicas recommendations
+func (e *CostEstimator) Estimate(recommendations []analysisv1alph1.Recommendation) ([]WorkloadCost, error) {
+	type workloadRecommendations struct {
+		targetRef corev1.ObjectReference
+		resource  *analysisv1alph1.Recommendation
+		replicas  *analysisv1alph1.Recommendation
+	}
+
+	var keys []string
+	workloads := map[string]*workloadRecommendations{}
+	for i := range recommendations {
+		recommendation := &recommendations[i]
+		if recommendation.Spec.Type != analysisv1alph1.AnalysisTypeResource && recommendation.Spec.Type != analysisv1alph1.AnalysisTypeReplicas {
+			continue
+		}
+
+		key := GetObjectRefKey("", recommendation.Spec.TargetRef)
+		workload, exist := workloads[key]
+		if !exist {
+			workload = &workloadRecommendations{targetRef: recommendation.Spec.TargetRef}
+			workloads[key] = workload
+			keys = append(keys, key)
+		}
+		if recommendation.Spec.Type == analysisv1alph1.AnalysisTypeResource {
+			workload.resource = recommendation
+		} else {
+			workload.replicas = recommendation
+		}
+	}
+	sort.Strings(keys)
+
+	var costs []WorkloadCost
+	for _, key := range keys {
+		workload := workloads[key]
+		cost, err := e.estimateWorkload(workload.targetRef, workload.resource, workload.replicas)
+		if err != nil {
+			klog.Warningf("Failed to estimate the cost of %s %s/%s, %v.", workload.targetRef.Kind, workload.targetRef.Namespace, workload.targetRef.Name, err)
+			continue
+		}
+		costs = append(costs, *cost)
+	}
+
+	return costs, nil
+}
+
+func (e *CostEstimator) estimateWorkload(targetRef corev1.ObjectReference, resourceRecommend, replicasRecommend *analysisv1alph1.Recommendation) (*WorkloadCost, error) {
+	cost := &WorkloadCost{TargetRef: targetRef}
+
+	live, err := e.getWorkload(targetRef)
+	if err != nil {
+		// the recommendations still carry the requests and replicas of their last run
+		klog.Warningf("Failed to get %s %s/%s, %v.", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+	}
+
+	// the current requests are read from the workload, the ones recorded by the Resource recommendation
+	// are stale after an adoption or a manual change and only used when the workload can't be read
+	var current, recommended []utils.ContainerRequest
+	if live != nil {
+		current, err = utils.WorkloadContainerRequests(live)
+		if err != nil {
+			return nil, err
+		}
+	} else if resourceRecommend != nil {
+		current, err = utils.DecodeResourceInfo(resourceRecommend.Status.CurrentInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resourceRecommend != nil {
+		cost.Rule = resourceRecommend.Labels[recommend.RecommendationRuleNameLabel]
+
+		recommended, err = utils.DecodeResourceInfo(resourceRecommend.Status.RecommendedInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cpu, memory, recommendedCpu, recommendedMemory resource.Quantity
+	for _, container := range current {
+		cpu.Add(container.Cpu)
+		memory.Add(container.Memory)
+
+		// the resources without recommended value are kept as they are
+		containerCpu, containerMemory := container.Cpu, container.Memory
+		for _, recommendedContainer := range recommended {
+			if recommendedContainer.Name != container.Name {
+				continue
+			}
+			if !recommendedContainer.Cpu.IsZero() {
+				containerCpu = recommendedContainer.Cpu
+			}
+			if !recommendedContainer.Memory.IsZero() {
+				containerMemory = recommendedContainer.Memory
+			}
+		}
+		recommendedCpu.Add(containerCpu)
+		recommendedMemory.Add(containerMemory)
+
+		cost.Containers = append(cost.Containers, container)
+		cost.RecommendedContainers = append(cost.RecommendedContainers, utils.ContainerRequest{
+			Name:   container.Name,
+			Cpu:    containerCpu,
+			Memory: containerMemory,
+		})
+	}
+
+	cost.Replicas = 1
+	if live != nil {
+		cost.Replicas = utils.WorkloadReplicas(live)
+		cost.Labels = live.GetLabels()
+	}
+	cost.RecommendedReplicas = cost.Replicas
+	if replicasRecommend != nil {
+		if len(cost.Rule) == 0 {
+			cost.Rule = replicasRecommend.Labels[recommend.RecommendationRuleNameLabel]
+		}
+
+		// like the requests, the replicas recorded by the recommendation are only used when the workload can't be read
+		if live == nil {
+			if replicas, err := utils.DecodeReplicasInfo(replicasRecommend.Status.CurrentInfo); err == nil {
+				cost.Replicas = replicas
+			}
+		}
+		recommendedReplicas, err := utils.DecodeReplicasInfo(replicasRecommend.Status.RecommendedInfo)
+		if err != nil {
+			return nil, err
+		}
+		cost.RecommendedReplicas = recommendedReplicas
+	}
+
+	cost.Cpu = multiplyQuantity(cpu, cost.Replicas, resource.DecimalSI)
+	cost.Memory = multiplyQuantity(memory, cost.Replicas, resource.BinarySI)
+	cost.RecommendedCpu = multiplyQuantity(recommendedCpu, cost.RecommendedReplicas, resource.DecimalSI)
+	cost.RecommendedMemory = multiplyQuantity(recommendedMemory, cost.RecommendedReplicas, resource.BinarySI)
+
+	if live != nil && len(e.priceModel.NodeTypes) > 0 {
+		cost.NodeType, err = e.getNodeType(live)
+		if err != nil {
+			klog.Warningf("Failed to get the node type of %s %s/%s, %v.", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+		}
+	}
+
+	price := e.priceModel.PriceOf(cost.NodeType)
+	cost.Cost = price.MonthlyCost(cost.Cpu, cost.Memory)
+	cost.RecommendedCost = price.MonthlyCost(cost.RecommendedCpu, cost.RecommendedMemory)
+
+	return cost, nil
+}
+
+func (e *CostEstimator) getWorkload(targetRef corev1.ObjectReference) (*unstructured.Unstructured, error) {
+	gvrKey := targetRef.APIVersion + "/" + targetRef.Kind
+	gvr, exist := e.gvrs[gvrKey]
+	if !exist {
+		var err error
+		gvr, err = utils.GetGroupVersionResource(e.commonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+		if err != nil {
+			return nil, err
+		}
+		e.gvrs[gvrKey] = gvr
+	}
+
+	return e.commonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+}
+
+// getNodeType returns the type of the node the first scheduled pod of the workload runs on
+func (e *CostEstimator) getNodeType(live *unstructured.Unstructured) (string, error) {
+	if e.nodeTypes == nil {
+		nodeList, err := e.commonOptions.KubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+		e.nodeTypes = map[string]string{}
+		for _, node := range nodeList.Items {
+			e.nodeTypes[node.Name] = node.Labels[e.nodeTypeLabel]
+		}
+	}
+
+	matchLabels, _, err := unstructured.NestedStringMap(live.Object, "spec", "selector", "matchLabels")
+	if err != nil {
+		return "", err
+	}
+	if len(matchLabels) == 0 {
+		return "", nil
+	}
+
+	podList, err := e.commonOptions.KubeClient.CoreV1().Pods(live.GetNamespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(matchLabels).String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, pod := range podList.Items {
+		if len(pod.Spec.NodeName) > 0 {
+			return e.nodeTypes[pod.Spec.NodeName], nil
+		}
+	}
+
+	return "", nil
+}
+
+func multiplyQuantity(quantity resource.Quantity, replicas int32, format resource.Format) resource.Quantity {
+	if format == resource.DecimalSI {
+		return *resource.NewMilliQuantity(quantity.MilliValue()*int64(replicas), format)
+	}
+
+	return *resource.NewQuantity(quantity.Value()*int64(replicas), format)
+}
+
+// GroupWorkloadCosts sums the costs of the workloads by the group name, ordered by the savings
+func GroupWorkloadCosts(costs []WorkloadCost, groupName func(cost *WorkloadCost) string) []CostGroup {
+	var groups []CostGroup
+	index := map[string]int{}
+	for i := range costs {
+		name := groupName(&costs[i])
+		if _, exist := index[name]; !exist {
+			index[name] = len(groups)
+			groups = append(groups, CostGroup{Name: name})
+		}
+
+		group := &groups[index[name]]
+		group.Workloads++
+		group.Cost += costs[i].Cost
+		group.RecommendedCost += costs[i].RecommendedCost
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Savings() > groups[j].Savings()
+	})
+
+	return groups
+}
+
+func renderWorkloadCosts(costs []WorkloadCost, o *CostOptions) {
+	t := newCostTable(o)
+	t.AppendHeader(table.Row{"NAMESPACE", "KIND", "NAME", "RULE", "NODE TYPE", "REPLICAS", "CPU", "MEMORY", "RECOMMEND REPLICAS", "RECOMMEND CPU", "RECOMMEND MEMORY", "MONTHLY COST", "RECOMMEND MONTHLY COST", "MONTHLY SAVINGS"})
+
+	var total CostGroup
+	for i := range costs {
+		cost := &costs[i]
+		t.AppendRow(table.Row{cost.TargetRef.Namespace, cost.TargetRef.Kind, cost.TargetRef.Name, cost.Rule, cost.NodeType,
+			cost.Replicas, PrintQuantity(&cost.Cpu), PrintQuantity(&cost.Memory),
+			cost.RecommendedReplicas, PrintQuantity(&cost.RecommendedCpu), PrintQuantity(&cost.RecommendedMemory),
+			printCost(cost.Cost), printCost(cost.RecommendedCost), printSavings(cost.Savings(), cost.Cost)})
+
+		total.Workloads++
+		total.Cost += cost.Cost
+		total.RecommendedCost += cost.RecommendedCost
+	}
+
+	t.AppendFooter(table.Row{"Total", "", fmt.Sprintf("%d workloads", total.Workloads), "", "", "", "", "", "", "", "",
+		printCost(total.Cost), printCost(total.RecommendedCost), printSavings(total.Savings(), total.Cost)})
+	t.Render()
+}
+
+func renderCostGroups(groupHeader string, groups []CostGroup, o *CostOptions) {
+	t := newCostTable(o)
+	t.AppendHeader(table.Row{groupHeader, "WORKLOADS", "MONTHLY COST", "RECOMMEND MONTHLY COST", "MONTHLY SAVINGS"})
+
+	var total CostGroup
+	for i := range groups {
+		group := &groups[i]
+		t.AppendRow(table.Row{group.Name, group.Workloads, printCost(group.Cost), printCost(group.RecommendedCost), printSavings(group.Savings(), group.Cost)})
+
+		total.Workloads += group.Workloads
+		total.Cost += group.Cost
+		total.RecommendedCost += group.RecommendedCost
+	}
+
+	t.AppendFooter(table.Row{"Total", total.Workloads, printCost(total.Cost), printCost(total.RecommendedCost), printSavings(total.Savings(), total.Cost)})
+	t.Render()
+}
+
+func newCostTable(o *CostOptions) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(o.CommonOptions.Out)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Name: "MONTHLY COST", Align: text.AlignRight, AlignFooter: text.AlignRight},
+		{Name: "RECOMMEND MONTHLY COST", Align: text.AlignRight, AlignFooter: text.AlignRight},
+		{Name: "MONTHLY SAVINGS", Align: text.AlignRight, AlignFooter: text.AlignRight},
+	})
+
+	return t
+}
+
+func printCost(cost float64) string {
+	return fmt.Sprintf("%.2f", cost)
+}
+
+func printSavings(savings, cost float64) string {
+	if cost == 0 {
+		return printCost(savings)
+	}
+
+	return fmt.Sprintf("%.2f (%.1f%%)", savings, savings/cost*100)
+}
+
+func (o *CostOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64VarP(&o.CpuPrice, "cpu-price", "", utils.DefaultCpuPrice, "The price of one vCPU per hour")
+	cmd.Flags().Float64VarP(&o.MemoryPrice, "memory-price", "", utils.DefaultMemoryPrice, "The price of one GiB of memory per hour")
+	cmd.Flags().StringVarP(&o.NodePricesFile, "node-prices", "", "", "The yaml file with the prices of vCPU and memory per node type, the workloads running on other nodes use the default prices")
+	cmd.Flags().StringVarP(&o.NodeTypeLabel, "node-type-label", "", utils.DefaultNodeTypeLabel, "The label of nodes holding the node type")
+	cmd.Flags().StringVarP(&o.GroupBy, "group-by", "", CostGroupByWorkload, "Group the costs by workload, namespace or rule")
+	o.FilterOptions.AddFlags(cmd)
+}
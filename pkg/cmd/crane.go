@@ -29,11 +29,16 @@ func NewCraneCommand() *cobra.Command {
 		Short:        "Kubectl plugin for crane, including recommendation and cost estimate.",
 	}
 
-	//cmd.AddCommand(NewCmdCranePod())
-	//cmd.AddCommand(NewCmdCraneWorkload())
+	cmd.AddCommand(NewCmdCranePod())
+	cmd.AddCommand(NewCmdCraneWorkload())
 	cmd.AddCommand(NewCmdRecommendationRule())
+	cmd.AddCommand(NewCmdEHPA())
 	cmd.AddCommand(NewCmdRecommend())
 	cmd.AddCommand(NewCmdViewRecommend())
+	cmd.AddCommand(NewCmdPredict())
+	cmd.AddCommand(NewCmdCost())
+	cmd.AddCommand(NewCmdReport())
+	cmd.AddCommand(NewCmdSnapshot())
 	cmd.AddCommand(NewCmdVersion())
 
 	return cmd
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/ehpa"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+type EHPAOptions struct {
+	CommonOptions *options.CommonOptions
+}
+
+func NewEHPAOptions() *EHPAOptions {
+	return &EHPAOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdEHPA() *cobra.Command {
+	ehpaOptions := NewEHPAOptions()
+
+	cmd := &cobra.Command{
+		Use:     "effectivehpa",
+		Aliases: []string{"ehpa"},
+		Short:   "manage effective horizontal pod autoscalers",
+	}
+	ehpaOptions.CommonOptions.AddCommonFlag(cmd)
+
+	cmd.AddCommand(ehpa.NewCmdEHPAList())
+	cmd.AddCommand(ehpa.NewCmdEHPADescribe())
+	cmd.AddCommand(ehpa.NewCmdEHPACreate())
+	cmd.AddCommand(ehpa.NewCmdEHPASetStrategy())
+
+	return cmd
+}
@@ -0,0 +1,163 @@
+package ehpa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/klog/v2"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
+	cranescheme "github.com/gocrane/api/pkg/generated/clientset/versioned/scheme"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+)
+
+var (
+	ehpaCreateExample = `
+# create the effective hpa proposed by a Replicas recommendation
+%[1]s ehpa create --from-recommendation workloads-rule-replicas-p84jv -n default
+
+# create the effective hpa in preview strategy, it only reports the desired replicas
+%[1]s ehpa create --from-recommendation workloads-rule-replicas-p84jv -n default --strategy Preview
+
+# pre-commit
+%[1]s ehpa create --from-recommendation workloads-rule-replicas-p84jv -n default --dry-run
+`
+)
+
+type EHPACreateOptions struct {
+	CommonOptions *options.CommonOptions
+
+	FromRecommendation string
+	Name               string
+	Strategy           string
+	DryRun             bool
+}
+
+func NewEHPACreateOptions() *EHPACreateOptions {
+	return &EHPACreateOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdEHPACreate() *cobra.Command {
+	o := NewEHPACreateOptions()
+
+	command := &cobra.Command{
+		Use:     "create",
+		Short:   "create an effective hpa from a recommendation",
+		Example: fmt.Sprintf(ehpaCreateExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+ehpaCreateExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *EHPACreateOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.FromRecommendation) == 0 {
+		return errors.New("please specify the recommendation with --from-recommendation")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+		return errors.New("please specify the recommendation namespace")
+	}
+
+	if err := ValidateScaleStrategy(o.Strategy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *EHPACreateOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *EHPACreateOptions) Run() error {
+	recommendation, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.FromRecommendation, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the recommendation %s, %v", o.FromRecommendation, err)
+	}
+
+	if string(recommendation.Spec.Type) != analysisv1alpha1.ReplicasRecommender && string(recommendation.Spec.Type) != analysisv1alpha1.HPARecommender {
+		return fmt.Errorf("recommendation type %s proposes no effective hpa, please specify a %s or %s recommendation", recommendation.Spec.Type, analysisv1alpha1.ReplicasRecommender, analysisv1alpha1.HPARecommender)
+	}
+
+	autoscaler, err := recommend.FindAutoscaler(o.CommonOptions, recommendation.Spec.TargetRef)
+	if err != nil {
+		return err
+	}
+	if autoscaler != nil {
+		return fmt.Errorf("the target is already scaled by the %s, adopt the recommendation with 'recommend adopt --name %s' to update it", autoscaler, recommendation.Name)
+	}
+
+	proposed, err := recommend.ProposedAutoscaling(recommendation)
+	if err != nil {
+		return err
+	}
+
+	ehpa, err := recommend.BuildEffectiveHPA(recommendation, proposed)
+	if err != nil {
+		return err
+	}
+	if len(o.Name) > 0 {
+		ehpa.Name = o.Name
+	}
+	ehpa.Spec.ScaleStrategy = autoscalingapi.ScaleStrategy(o.Strategy)
+
+	createOptions := metav1.CreateOptions{}
+	if o.DryRun {
+		createOptions.DryRun = []string{"All"}
+	}
+
+	created, err := o.CommonOptions.CraneClient.AutoscalingV1alpha1().EffectiveHorizontalPodAutoscalers(ehpa.Namespace).Create(context.TODO(), ehpa, createOptions)
+	if err != nil {
+		return fmt.Errorf("failed to create the effective hpa %s, %v", ehpa.Name, err)
+	}
+
+	// when dry-run set, print the object
+	if o.DryRun {
+		printer := printers.NewTypeSetter(cranescheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		return printer.PrintObj(created, o.CommonOptions.Out)
+	}
+
+	klog.Infof("effective hpa %s/%s created", created.Namespace, created.Name)
+	return nil
+}
+
+func (o *EHPACreateOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.FromRecommendation, "from-recommendation", "", "", "Specify the Replicas recommendation proposing the effective hpa")
+	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for effective hpa, the name of the target by default")
+	cmd.Flags().StringVarP(&o.Strategy, "strategy", "", string(autoscalingapi.ScaleStrategyAuto), "Specify the scale strategy[Auto, Preview]")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+}
@@ -0,0 +1,212 @@
+package ehpa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/klog/v2"
+
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
+	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+var (
+	ehpaDescribeExample = `
+# show the metrics, conditions and prediction of the effective hpa
+%[1]s ehpa describe nginx -n default
+`
+)
+
+type EHPADescribeOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Name string
+}
+
+func NewEHPADescribeOptions() *EHPADescribeOptions {
+	return &EHPADescribeOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdEHPADescribe() *cobra.Command {
+	o := NewEHPADescribeOptions()
+
+	command := &cobra.Command{
+		Use:     "describe <ehpa>",
+		Short:   "show the details of an effective hpa",
+		Example: fmt.Sprintf(ehpaDescribeExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+ehpaDescribeExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+
+	return command
+}
+
+func (o *EHPADescribeOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the effective hpa name")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+		return errors.New("please specify the effective hpa namespace")
+	}
+
+	return nil
+}
+
+func (o *EHPADescribeOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	return nil
+}
+
+func (o *EHPADescribeOptions) Run() error {
+	ehpa, err := o.CommonOptions.CraneClient.AutoscalingV1alpha1().EffectiveHorizontalPodAutoscalers(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the effective hpa %s, %v", o.Name, err)
+	}
+
+	out := o.CommonOptions.Out
+	now := time.Now()
+	renderSummary(ehpa, now, out)
+
+	fmt.Fprintln(out, "\nMetrics:")
+	renderMetrics(ehpa, out)
+
+	if len(ehpa.Spec.Crons) > 0 {
+		fmt.Fprintln(out, "\nCrons:")
+		renderCrons(ehpa, out)
+	}
+
+	fmt.Fprintln(out, "\nConditions:")
+	renderConditions(ehpa.Status.Conditions, now, out)
+
+	return nil
+}
+
+func renderSummary(ehpa *autoscalingapi.EffectiveHorizontalPodAutoscaler, now time.Time, out io.Writer) {
+	lastScale := "<none>"
+	if ehpa.Status.LastScaleTime != nil {
+		lastScaleTime := ehpa.Status.LastScaleTime.Time
+		lastScale = fmt.Sprintf("%s (%s ago)", lastScaleTime.Format(time.RFC3339), duration.HumanDuration(now.Sub(lastScaleTime)))
+	}
+
+	target := ehpa.Spec.ScaleTargetRef
+	fmt.Fprintf(out, "%-20s%s\n", "Name:", ehpa.Name)
+	fmt.Fprintf(out, "%-20s%s\n", "Namespace:", ehpa.Namespace)
+	fmt.Fprintf(out, "%-20s%s/%s (%s)\n", "Target:", target.Kind, target.Name, target.APIVersion)
+	fmt.Fprintf(out, "%-20s%s\n", "Scale Strategy:", ehpa.Spec.ScaleStrategy)
+	fmt.Fprintf(out, "%-20s%s\n", "Min Replicas:", printReplicas(ehpa.Spec.MinReplicas))
+	fmt.Fprintf(out, "%-20s%d\n", "Max Replicas:", ehpa.Spec.MaxReplicas)
+	if ehpa.Spec.ScaleStrategy == autoscalingapi.ScaleStrategyPreview {
+		fmt.Fprintf(out, "%-20s%s\n", "Specific Replicas:", printReplicas(ehpa.Spec.SpecificReplicas))
+	}
+	fmt.Fprintf(out, "%-20s%s\n", "Current Replicas:", printReplicas(ehpa.Status.CurrentReplicas))
+	fmt.Fprintf(out, "%-20s%s\n", "Desired Replicas:", printReplicas(ehpa.Status.ExpectReplicas))
+	fmt.Fprintf(out, "%-20s%s\n", "Last Scale:", lastScale)
+	fmt.Fprintf(out, "%-20s%s\n", "Prediction:", PredictionStatus(ehpa))
+
+	prediction := ehpa.Spec.Prediction
+	if prediction == nil {
+		return
+	}
+	if prediction.PredictionWindowSeconds != nil {
+		window := time.Duration(*prediction.PredictionWindowSeconds) * time.Second
+		fmt.Fprintf(out, "%-20s%s\n", "  Window:", window)
+	}
+	if algorithm := prediction.PredictionAlgorithm; algorithm != nil {
+		fmt.Fprintf(out, "%-20s%s\n", "  Algorithm:", algorithm.AlgorithmType)
+		switch {
+		case algorithm.AlgorithmType == predictionapi.AlgorithmTypeDSP && algorithm.DSP != nil:
+			fmt.Fprintf(out, "%-20s%s\n", "  Sample Interval:", algorithm.DSP.SampleInterval)
+			fmt.Fprintf(out, "%-20s%s\n", "  History Length:", algorithm.DSP.HistoryLength)
+		case algorithm.AlgorithmType == predictionapi.AlgorithmTypePercentile && algorithm.Percentile != nil:
+			fmt.Fprintf(out, "%-20s%s\n", "  Sample Interval:", algorithm.Percentile.SampleInterval)
+			fmt.Fprintf(out, "%-20s%s\n", "  History Length:", algorithm.Percentile.HistoryLength)
+			fmt.Fprintf(out, "%-20s%s\n", "  Percentile:", algorithm.Percentile.Percentile)
+		}
+	}
+}
+
+func renderMetrics(ehpa *autoscalingapi.EffectiveHorizontalPodAutoscaler, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"TYPE", "TARGET"})
+
+	for _, metric := range ehpa.Spec.Metrics {
+		t.AppendRow(table.Row{metric.Type, MetricTarget(metric)})
+	}
+
+	t.Render()
+}
+
+func renderCrons(ehpa *autoscalingapi.EffectiveHorizontalPodAutoscaler, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"NAME", "TIMEZONE", "START", "END", "TARGET REPLICAS", "DESCRIPTION"})
+
+	for _, cron := range ehpa.Spec.Crons {
+		timezone := "UTC"
+		if cron.TimeZone != nil {
+			timezone = *cron.TimeZone
+		}
+		t.AppendRow(table.Row{cron.Name, timezone, cron.Start, cron.End, cron.TargetReplicas, cron.Description})
+	}
+
+	t.Render()
+}
+
+func renderConditions(conditions []metav1.Condition, now time.Time, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"TYPE", "STATUS", "REASON", "LAST TRANSITION", "MESSAGE"})
+
+	for _, condition := range conditions {
+		t.AppendRow(table.Row{
+			condition.Type,
+			condition.Status,
+			condition.Reason,
+			duration.HumanDuration(now.Sub(condition.LastTransitionTime.Time)) + " ago",
+			condition.Message,
+		})
+	}
+
+	t.Render()
+}
@@ -0,0 +1,166 @@
+package ehpa
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
+)
+
+const (
+	StatusDisabled = "Disabled"
+	StatusPending  = "Pending"
+	StatusReady    = "Ready"
+	StatusNotReady = "NotReady"
+)
+
+// PredictionStatus returns whether the prediction of the EHPA is ready, or disabled when not configured
+func PredictionStatus(ehpa *autoscalingapi.EffectiveHorizontalPodAutoscaler) string {
+	if ehpa.Spec.Prediction == nil {
+		return StatusDisabled
+	}
+
+	return conditionStatus(ehpa.Status.Conditions, string(autoscalingapi.PredictionReady))
+}
+
+// ReadyStatus returns whether the EHPA is scaling the target as expected
+func ReadyStatus(ehpa *autoscalingapi.EffectiveHorizontalPodAutoscaler) string {
+	return conditionStatus(ehpa.Status.Conditions, string(autoscalingapi.Ready))
+}
+
+func conditionStatus(conditions []metav1.Condition, conditionType string) string {
+	condition := meta.FindStatusCondition(conditions, conditionType)
+	switch {
+	case condition == nil:
+		return StatusPending
+	case condition.Status == metav1.ConditionTrue:
+		return StatusReady
+	default:
+		return StatusNotReady
+	}
+}
+
+// MetricTarget describes the metric and its target, e.g. cpu: 60%
+func MetricTarget(metric autoscalingv2.MetricSpec) string {
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource != nil {
+			return fmt.Sprintf("%s: %s", metric.Resource.Name, targetValue(metric.Resource.Target))
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if metric.ContainerResource != nil {
+			return fmt.Sprintf("%s/%s: %s", metric.ContainerResource.Container, metric.ContainerResource.Name, targetValue(metric.ContainerResource.Target))
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods != nil {
+			return fmt.Sprintf("pods/%s: %s", metric.Pods.Metric.Name, targetValue(metric.Pods.Target))
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object != nil {
+			return fmt.Sprintf("%s/%s/%s: %s", strings.ToLower(metric.Object.DescribedObject.Kind), metric.Object.DescribedObject.Name, metric.Object.Metric.Name, targetValue(metric.Object.Target))
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External != nil {
+			return fmt.Sprintf("external/%s: %s", metric.External.Metric.Name, targetValue(metric.External.Target))
+		}
+	}
+
+	return string(metric.Type)
+}
+
+func targetValue(target autoscalingv2.MetricTarget) string {
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return target.AverageValue.String() + " (avg)"
+	case target.Value != nil:
+		return target.Value.String()
+	}
+
+	return "<unset>"
+}
+
+func printReplicas(replicas *int32) string {
+	if replicas == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d", *replicas)
+}
+
+func renderTable(ehpas []autoscalingapi.EffectiveHorizontalPodAutoscaler, out io.Writer, wide bool) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(tableHeader(wide))
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{
+			Name:        "NAME",
+			Align:       text.AlignLeft,
+			AlignFooter: text.AlignLeft,
+			AlignHeader: text.AlignLeft,
+			VAlign:      text.VAlignMiddle,
+			WidthMin:    6,
+			WidthMax:    24,
+		},
+	})
+
+	for _, ehpa := range ehpas {
+		t.AppendRows([]table.Row{
+			tableRow(ehpa, wide),
+		})
+
+		t.AppendSeparator()
+	}
+
+	t.Render()
+}
+
+func tableHeader(wide bool) table.Row {
+	header := table.Row{"NAME", "NAMESPACE", "TARGET KIND", "TARGET NAME", "STRATEGY", "MIN", "MAX", "SPECIFIC", "CURRENT", "DESIRED", "PREDICTION", "READY", "CREATED TIME"}
+	if wide {
+		header = append(header, table.Row{"METRICS", "CRONS", "LAST SCALE TIME"}...)
+	}
+
+	return header
+}
+
+func tableRow(ehpa autoscalingapi.EffectiveHorizontalPodAutoscaler, wide bool) table.Row {
+	row := table.Row{
+		ehpa.Name,
+		ehpa.Namespace,
+		ehpa.Spec.ScaleTargetRef.Kind,
+		ehpa.Spec.ScaleTargetRef.Name,
+		ehpa.Spec.ScaleStrategy,
+		printReplicas(ehpa.Spec.MinReplicas),
+		ehpa.Spec.MaxReplicas,
+		printReplicas(ehpa.Spec.SpecificReplicas),
+		printReplicas(ehpa.Status.CurrentReplicas),
+		printReplicas(ehpa.Status.ExpectReplicas),
+		PredictionStatus(&ehpa),
+		ReadyStatus(&ehpa),
+		ehpa.CreationTimestamp,
+	}
+
+	if wide {
+		var metrics []string
+		for _, metric := range ehpa.Spec.Metrics {
+			metrics = append(metrics, MetricTarget(metric))
+		}
+		lastScaleTime := "<none>"
+		if ehpa.Status.LastScaleTime != nil {
+			lastScaleTime = ehpa.Status.LastScaleTime.String()
+		}
+		row = append(row, strings.Join(metrics, "\n"), len(ehpa.Spec.Crons), lastScaleTime)
+	}
+
+	return row
+}
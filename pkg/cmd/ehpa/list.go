@@ -0,0 +1,161 @@
+package ehpa
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	ehpaListExample = `
+# view the effective hpa in default namespace
+%[1]s ehpa list
+
+# view the effective hpa across all namespaces with their metrics
+%[1]s ehpa list -A -o wide
+
+# view the effective hpa in preview strategy
+%[1]s ehpa list -n kube-system --strategy Preview
+
+# output the effective hpa selected by labels as yaml
+%[1]s ehpa list -l app=nginx -o yaml
+`
+)
+
+type EHPAListOptions struct {
+	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
+
+	Name          string
+	Strategy      string
+	LabelSelector string
+	AllNamespaces bool
+}
+
+func NewEHPAListOptions() *EHPAListOptions {
+	return &EHPAListOptions{
+		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
+	}
+}
+
+func NewCmdEHPAList() *cobra.Command {
+	o := NewEHPAListOptions()
+
+	command := &cobra.Command{
+		Use:     "list",
+		Short:   "view effective hpa",
+		Example: fmt.Sprintf(ehpaListExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+ehpaListExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.PrintOptions.AddPrintFlags(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *EHPAListOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Strategy) > 0 {
+		if err := ValidateScaleStrategy(o.Strategy); err != nil {
+			return err
+		}
+	}
+
+	if len(o.LabelSelector) > 0 {
+		if _, err := labels.Parse(o.LabelSelector); err != nil {
+			return fmt.Errorf("invalid label selector %s, %v", o.LabelSelector, err)
+		}
+	}
+
+	return nil
+}
+
+func (o *EHPAListOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *EHPAListOptions) Run() error {
+	namespace := *o.CommonOptions.ConfigFlags.Namespace
+	if o.AllNamespaces {
+		namespace = ""
+	}
+
+	ehpaList, err := o.CommonOptions.CraneClient.AutoscalingV1alpha1().EffectiveHorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: o.LabelSelector,
+	})
+	if err != nil {
+		klog.Errorf("Failed to get effective hpa, %v.", err)
+		return err
+	}
+
+	query := utils.NewQuery()
+	if len(o.Name) > 0 {
+		query.Filters[utils.FieldName] = utils.Value(o.Name)
+	}
+
+	var ehpas []autoscalingapi.EffectiveHorizontalPodAutoscaler
+	for _, ehpa := range ehpaList.Items {
+		selected := len(o.Strategy) == 0 || string(ehpa.Spec.ScaleStrategy) == o.Strategy
+		for field, value := range query.Filters {
+			if !utils.ObjectMetaFilter(ehpa.ObjectMeta, utils.Filter{Field: field, Value: value}) {
+				selected = false
+				break
+			}
+		}
+
+		if selected {
+			ehpas = append(ehpas, ehpa)
+		}
+	}
+
+	if !o.PrintOptions.IsTable() {
+		return o.PrintOptions.PrintObj(&autoscalingapi.EffectiveHorizontalPodAutoscalerList{Items: ehpas}, o.CommonOptions.Out)
+	}
+
+	renderTable(ehpas, o.CommonOptions.Out, o.PrintOptions.IsWide())
+
+	return nil
+}
+
+func (o *EHPAListOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for effective hpa")
+	cmd.Flags().StringVarP(&o.Strategy, "strategy", "", "", "Select effective hpa with specify scale strategy[Auto, Preview]")
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", "", "Select effective hpa by the label selector")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+}
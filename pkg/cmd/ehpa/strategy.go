@@ -0,0 +1,213 @@
+package ehpa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/klog/v2"
+
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
+	cranescheme "github.com/gocrane/api/pkg/generated/clientset/versioned/scheme"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+var (
+	ehpaSetStrategyExample = `
+# stop scaling the target, the effective hpa only reports the desired replicas
+%[1]s ehpa set-strategy Preview nginx -n default
+
+# hold the target at 3 replicas
+%[1]s ehpa set-strategy Preview nginx -n default --specific-replicas 3
+
+# let the effective hpa selected by labels scale their targets
+%[1]s ehpa set-strategy Auto -l team=web -n default
+
+# pre-commit
+%[1]s ehpa set-strategy Auto nginx -n default --dry-run
+`
+)
+
+// ValidateScaleStrategy ensures that the strategy is one of Auto and Preview
+func ValidateScaleStrategy(strategy string) error {
+	switch autoscalingapi.ScaleStrategy(strategy) {
+	case autoscalingapi.ScaleStrategyAuto, autoscalingapi.ScaleStrategyPreview:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported scale strategy %s, please specify one of %s, %s", strategy, autoscalingapi.ScaleStrategyAuto, autoscalingapi.ScaleStrategyPreview)
+}
+
+type EHPASetStrategyOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Strategy         string
+	Names            []string
+	LabelSelector    string
+	SpecificReplicas int32
+	DryRun           bool
+}
+
+func NewEHPASetStrategyOptions() *EHPASetStrategyOptions {
+	return &EHPASetStrategyOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdEHPASetStrategy() *cobra.Command {
+	o := NewEHPASetStrategyOptions()
+
+	command := &cobra.Command{
+		Use:     "set-strategy Auto|Preview [<ehpa>...]",
+		Short:   "switch the scale strategy of effective hpa",
+		Example: fmt.Sprintf(ehpaSetStrategyExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+ehpaSetStrategyExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *EHPASetStrategyOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Strategy) == 0 {
+		return errors.New("please specify the scale strategy")
+	}
+
+	if err := ValidateScaleStrategy(o.Strategy); err != nil {
+		return err
+	}
+
+	if len(o.Names) == 0 && len(o.LabelSelector) == 0 {
+		return errors.New("please specify the effective hpa names or the label selector")
+	}
+
+	if len(o.Names) > 0 && len(o.LabelSelector) > 0 {
+		return errors.New("the effective hpa names can't be used together with the label selector")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+		return errors.New("please specify the effective hpa namespace")
+	}
+
+	if len(o.LabelSelector) > 0 {
+		if _, err := labels.Parse(o.LabelSelector); err != nil {
+			return fmt.Errorf("invalid label selector %s, %v", o.LabelSelector, err)
+		}
+	}
+
+	if o.SpecificReplicas < 0 {
+		return errors.New("the specific replicas should not be negative")
+	}
+
+	if o.SpecificReplicas > 0 && o.Strategy != string(autoscalingapi.ScaleStrategyPreview) {
+		return fmt.Errorf("the specific replicas only work with the %s strategy", autoscalingapi.ScaleStrategyPreview)
+	}
+
+	return nil
+}
+
+func (o *EHPASetStrategyOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Strategy = args[0]
+		o.Names = args[1:]
+	}
+
+	return nil
+}
+
+func (o *EHPASetStrategyOptions) Run() error {
+	namespace := *o.CommonOptions.ConfigFlags.Namespace
+	client := o.CommonOptions.CraneClient.AutoscalingV1alpha1().EffectiveHorizontalPodAutoscalers(namespace)
+
+	names := o.Names
+	if len(o.LabelSelector) > 0 {
+		ehpaList, err := client.List(context.TODO(), metav1.ListOptions{LabelSelector: o.LabelSelector})
+		if err != nil {
+			return err
+		}
+		for _, ehpa := range ehpaList.Items {
+			names = append(names, ehpa.Name)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no effective hpa matches the label selector %s in namespace %s", o.LabelSelector, namespace)
+		}
+	}
+
+	// clear the specific replicas left by an earlier Preview, they would hold the target otherwise
+	spec := map[string]interface{}{"scaleStrategy": o.Strategy, "specificReplicas": nil}
+	if o.SpecificReplicas > 0 {
+		spec["specificReplicas"] = o.SpecificReplicas
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return err
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if o.DryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+
+	// switch the effective hpa one by one and continue past the failed ones
+	failed := 0
+	for _, name := range names {
+		patched, err := client.Patch(context.TODO(), name, types.MergePatchType, patch, patchOptions)
+		if err != nil {
+			klog.Errorf("Failed to set the scale strategy of effective hpa %s/%s, %v.", namespace, name, err)
+			failed++
+			continue
+		}
+
+		// when dry-run set, print the object
+		if o.DryRun {
+			printer := printers.NewTypeSetter(cranescheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+			if err := printer.PrintObj(patched, o.CommonOptions.Out); err != nil {
+				return err
+			}
+			continue
+		}
+		klog.Infof("effective hpa %s/%s scale strategy set to %s", namespace, name, o.Strategy)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to set the scale strategy of %d of %d effective hpa", failed, len(names))
+	}
+
+	return nil
+}
+
+func (o *EHPASetStrategyOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", "", "Select effective hpa by the label selector")
+	cmd.Flags().Int32VarP(&o.SpecificReplicas, "specific-replicas", "", 0, "The replicas to hold the target at with the Preview strategy")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+}
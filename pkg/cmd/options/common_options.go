@@ -24,10 +24,13 @@ type CommonOptions struct {
 	RestConfig *rest.Config
 	RestMapper meta.RESTMapper
 
-	KubeClient      *kubernetes.Clientset
-	CraneClient     *crane.Clientset
+	KubeClient      kubernetes.Interface
+	CraneClient     crane.Interface
 	DynamicClient   dynamic.Interface
 	DiscoveryClient discovery.DiscoveryInterface
+
+	// FromFile is the snapshot the objects are read from instead of the cluster
+	FromFile string
 }
 
 var defaultConfigFlags = genericclioptions.NewConfigFlags(true)
@@ -40,6 +43,10 @@ func NewCommonOptions() *CommonOptions {
 }
 
 func (o *CommonOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(o.FromFile) > 0 {
+		return o.completeFromFile()
+	}
+
 	var err error
 	o.RestConfig, err = o.ConfigFlags.ToRESTConfig()
 	if err != nil {
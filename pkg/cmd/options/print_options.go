@@ -0,0 +1,126 @@
+package options
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+
+	cranescheme "github.com/gocrane/api/pkg/generated/clientset/versioned/scheme"
+)
+
+const (
+	OutputFormatWide = "wide"
+)
+
+// printScheme knows both the kubernetes and the crane types, so that the
+// printers can fill in the kind of any object returned by the clientsets.
+var printScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(kubescheme.AddToScheme(printScheme))
+	utilruntime.Must(cranescheme.AddToScheme(printScheme))
+}
+
+// PrintOptions provides the kubectl style -o flags shared by the commands
+// that print crane objects. The empty and wide formats are rendered as a
+// table by the caller, the other formats are handled by PrintFlags.
+type PrintOptions struct {
+	PrintFlags *genericclioptions.PrintFlags
+}
+
+func NewPrintOptions() *PrintOptions {
+	return &PrintOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("").WithTypeSetter(printScheme),
+	}
+}
+
+// Validate ensures that the output format is supported
+func (o *PrintOptions) Validate() error {
+	if o.IsTable() {
+		return nil
+	}
+
+	if _, err := o.PrintFlags.ToPrinter(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IsTable returns true when the output should be rendered as a table
+func (o *PrintOptions) IsTable() bool {
+	outputFormat := *o.PrintFlags.OutputFormat
+	templateSpecified := o.PrintFlags.TemplatePrinterFlags.TemplateArgument != nil &&
+		len(*o.PrintFlags.TemplatePrinterFlags.TemplateArgument) > 0
+
+	return (outputFormat == "" && !templateSpecified) || outputFormat == OutputFormatWide
+}
+
+// IsWide returns true when the table should contain the extra columns
+func (o *PrintOptions) IsWide() bool {
+	return *o.PrintFlags.OutputFormat == OutputFormatWide
+}
+
+// PrintObj prints the object or the list with the printer selected by -o.
+func (o *PrintOptions) PrintObj(obj runtime.Object, out io.Writer) error {
+	printer, err := o.PrintFlags.ToPrinter()
+	if err != nil {
+		return err
+	}
+
+	if !meta.IsListType(obj) {
+		return printer.PrintObj(obj, out)
+	}
+
+	// the items of a list returned by the clientset don't carry their kind,
+	// which is required by the name printer and expected in json/yaml.
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := setGroupVersionKind(item); err != nil {
+			return err
+		}
+	}
+
+	// the name printer refuses typed lists, print the items one by one
+	if *o.PrintFlags.OutputFormat == "name" {
+		for _, item := range items {
+			if err := printer.PrintObj(item, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return printer.PrintObj(obj, out)
+}
+
+func (o *PrintOptions) AddPrintFlags(cmd *cobra.Command) {
+	o.PrintFlags.AddFlags(cmd)
+
+	allowedFormats := append(o.PrintFlags.AllowedFormats(), OutputFormatWide)
+	cmd.Flags().Lookup("output").Usage = fmt.Sprintf("Output format. One of: (%s).", strings.Join(allowedFormats, ", "))
+}
+
+func setGroupVersionKind(obj runtime.Object) error {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		return nil
+	}
+
+	gvks, _, err := printScheme.ObjectKinds(obj)
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package options
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	kubernetesscheme "k8s.io/client-go/kubernetes/scheme"
+
+	fakecrane "github.com/gocrane/api/pkg/generated/clientset/versioned/fake"
+	cranescheme "github.com/gocrane/api/pkg/generated/clientset/versioned/scheme"
+)
+
+// AddFromFileFlag adds the flag to read the objects from a snapshot instead of the cluster
+func (o *CommonOptions) AddFromFileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.FromFile, "from-file", "", "", "Read the recommendations, recommendation rules and workloads from a snapshot file or directory written by 'kubectl crane snapshot' instead of the cluster")
+}
+
+// completeFromFile builds the clients serving the objects in the snapshot, no api server is needed
+func (o *CommonOptions) completeFromFile() error {
+	objects, err := LoadSnapshot(o.FromFile)
+	if err != nil {
+		return err
+	}
+
+	var kubeObjects, craneObjects, dynamicObjects []runtime.Object
+	resources := map[string]*metav1.APIResourceList{}
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, object := range objects {
+		gvk := object.GroupVersionKind()
+
+		// the typed clients need the typed objects
+		if typed, err := toTyped(kubernetesscheme.Scheme, object); err == nil {
+			kubeObjects = append(kubeObjects, typed)
+		} else if typed, err := toTyped(cranescheme.Scheme, object); err == nil {
+			craneObjects = append(craneObjects, typed)
+		}
+		dynamicObjects = append(dynamicObjects, object)
+
+		// serve the kinds in the snapshot by the discovery
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		listKinds[plural] = gvk.Kind + "List"
+		resourceList, exist := resources[gvk.GroupVersion().String()]
+		if !exist {
+			resourceList = &metav1.APIResourceList{GroupVersion: gvk.GroupVersion().String()}
+			resources[gvk.GroupVersion().String()] = resourceList
+		}
+		found := false
+		for _, resource := range resourceList.APIResources {
+			if resource.Kind == gvk.Kind {
+				found = true
+			}
+		}
+		if !found {
+			resourceList.APIResources = append(resourceList.APIResources, metav1.APIResource{
+				Name:       plural.Resource,
+				Kind:       gvk.Kind,
+				Namespaced: len(object.GetNamespace()) > 0,
+				Verbs:      metav1.Verbs{"get", "list", "watch"},
+			})
+		}
+	}
+
+	kubeClient := fakekubernetes.NewSimpleClientset(kubeObjects...)
+	discoveryClient := kubeClient.Discovery().(*fakediscovery.FakeDiscovery)
+	for _, resourceList := range resources {
+		discoveryClient.Resources = append(discoveryClient.Resources, resourceList)
+	}
+
+	o.KubeClient = kubeClient
+	o.CraneClient = fakecrane.NewSimpleClientset(craneObjects...)
+	o.DynamicClient = fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, dynamicObjects...)
+	o.DiscoveryClient = discoveryClient
+
+	return nil
+}
+
+func toTyped(scheme *runtime.Scheme, object *unstructured.Unstructured) (runtime.Object, error) {
+	typed, err := scheme.New(object.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, typed); err != nil {
+		return nil, err
+	}
+
+	return typed, nil
+}
+
+// LoadSnapshot reads the objects in the yaml or json file, or in all the yaml and json files of the directory.
+// The lists like v1 List are expanded to their items.
+func LoadSnapshot(path string) ([]*unstructured.Unstructured, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(file)) {
+			case ".yaml", ".yml", ".json":
+				if !info.IsDir() {
+					files = append(files, file)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, file := range files {
+		fileObjects, err := loadSnapshotFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the snapshot %s, %v", file, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+
+	return objects, nil
+}
+
+func loadSnapshotFile(file string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var objects []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		raw := runtime.RawExtension{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		raw.Raw = bytes.TrimSpace(raw.Raw)
+		if len(raw.Raw) == 0 || bytes.Equal(raw.Raw, []byte("null")) {
+			continue
+		}
+
+		// the unstructured json scheme keeps the integers as int64
+		object, err := runtime.Decode(unstructured.UnstructuredJSONScheme, raw.Raw)
+		if err != nil {
+			return nil, err
+		}
+
+		switch object := object.(type) {
+		case *unstructured.UnstructuredList:
+			for i := range object.Items {
+				objects = append(objects, &object.Items[i])
+			}
+		case *unstructured.Unstructured:
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
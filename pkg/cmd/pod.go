@@ -13,16 +13,23 @@ import (
 
 	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
 	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
 )
 
 type CranePodOptions struct {
 	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
 	AllNamespaces bool
+
+	// the owners of the ReplicaSets keyed by namespace/name
+	replicaSetOwners map[string][]metav1.OwnerReference
 }
 
 func NewCranePodOptions() *CranePodOptions {
 	return &CranePodOptions{
-		CommonOptions: options.NewCommonOptions(),
+		CommonOptions:    options.NewCommonOptions(),
+		PrintOptions:     options.NewPrintOptions(),
+		replicaSetOwners: map[string][]metav1.OwnerReference{},
 	}
 }
 
@@ -51,6 +58,7 @@ func NewCmdCranePod() *cobra.Command {
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", o.AllNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
 
 	o.CommonOptions.AddCommonFlag(cmd)
+	o.PrintOptions.AddPrintFlags(cmd)
 
 	return cmd
 }
@@ -60,6 +68,10 @@ func (o *CranePodOptions) Validate() error {
 		return err
 	}
 
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -87,7 +99,7 @@ func (o *CranePodOptions) Run() error {
 		return err
 	}
 
-	recommendList, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations("").List(context.TODO(), metav1.ListOptions{})
+	recommendList, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(namespace).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		klog.Errorf("Failed to get recommends, %v.", err)
 		return err
@@ -98,6 +110,23 @@ func (o *CranePodOptions) Run() error {
 		recommendMap[GetObjectRefKey(string(recommend.Spec.Type), recommend.Spec.TargetRef)] = recommend
 	}
 
+	if !o.PrintOptions.IsTable() {
+		// print the resource recommendations of the workloads owning the pods
+		var recommendations []analysisv1alph1.Recommendation
+		selected := map[string]bool{}
+		for _, pod := range podList.Items {
+			for _, ref := range o.getWorkloadOwners(pod) {
+				key := GetOwnerKey(analysisv1alph1.ResourceRecommender, ref, pod.Namespace)
+				if recommendation, exist := recommendMap[key]; exist && !selected[key] {
+					selected[key] = true
+					recommendations = append(recommendations, recommendation)
+				}
+			}
+		}
+
+		return recommend.PrintRecommendations(recommendations, o.PrintOptions, recommend.NewHealthChecker(o.CommonOptions, o.PrintOptions.IsWide()), o.CommonOptions.Out)
+	}
+
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	t.SetOutputMirror(o.CommonOptions.Out)
@@ -106,6 +135,9 @@ func (o *CranePodOptions) Run() error {
 		header = append(header, "NAMESPACE")
 	}
 	header = append(header, table.Row{"NAME", "CONTAINER", "CPU", "MEMORY", "RECOMMEND CPU", "RECOMMEND MEMORY", "CPU DIFF", "MEMORY DIFF"}...)
+	if o.PrintOptions.IsWide() {
+		header = append(header, table.Row{"NODE", "OWNER"}...)
+	}
 	t.AppendHeader(header)
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{
@@ -135,7 +167,8 @@ func (o *CranePodOptions) Run() error {
 	diffMemoryTotal := resource.NewQuantity(0, resource.BinarySI)
 
 	for _, pod := range podList.Items {
-		resourceRecommendation := GetResourceRequestRecommendationsByPod(pod, recommendMap)
+		owners := o.getWorkloadOwners(pod)
+		resourceRecommendation := GetResourceRequestRecommendationsByOwners(owners, pod.Namespace, recommendMap)
 
 		for _, container := range pod.Spec.Containers {
 			row := table.Row{}
@@ -144,61 +177,34 @@ func (o *CranePodOptions) Run() error {
 			}
 			row = append(row, pod.Name)
 			row = append(row, container.Name)
-			containerCpu := resource.NewQuantity(0, resource.DecimalSI)
-			containerMemory := resource.NewQuantity(0, resource.BinarySI)
-			if container.Resources.Requests != nil {
-				requestCpu := container.Resources.Requests[corev1.ResourceCPU]
-				if !requestCpu.IsZero() {
-					containerCpu = &requestCpu
-				}
-				requestMemory := container.Resources.Requests[corev1.ResourceMemory]
-				if !requestMemory.IsZero() {
-					containerMemory = &requestMemory
-				}
-			}
+
+			containerCpu, containerMemory := ContainerRequests(container)
 			row = append(row, PrintQuantity(containerCpu))
 			row = append(row, PrintQuantity(containerMemory))
 			cpuTotal.Add(*containerCpu)
 			memoryTotal.Add(*containerMemory)
 
-			recCpu := resource.NewQuantity(0, resource.DecimalSI)
-			recMemory := resource.NewQuantity(0, resource.BinarySI)
-			if resourceRecommendation != nil {
-				for _, recContainer := range resourceRecommendation.Containers {
-					if recContainer.ContainerName == container.Name {
-						recCpuResource, err := resource.ParseQuantity(recContainer.Target[corev1.ResourceCPU])
-						if err == nil {
-							recCpu = &recCpuResource
-						}
-						recMemoryResource, err := resource.ParseQuantity(recContainer.Target[corev1.ResourceMemory])
-						if err == nil {
-							recMemory = &recMemoryResource
-						}
-					}
-				}
-			}
+			recCpu, recMemory := RecommendedContainerRequests(container.Name, resourceRecommendation)
 			row = append(row, PrintQuantity(recCpu))
 			row = append(row, PrintQuantity(recMemory))
 			recCpuTotal.Add(*recCpu)
 			recMemoryTotal.Add(*recMemory)
 
-			containerCpuDiff := resource.NewQuantity(0, resource.DecimalSI)
-			if !recCpu.IsZero() {
-				containerCpuDiff = containerCpu
-				containerCpuDiff.Sub(*recCpu)
-			}
-
-			containerMemoryDiff := resource.NewQuantity(0, resource.BinarySI)
-			if !recMemory.IsZero() {
-				containerMemoryDiff = containerMemory
-				containerMemoryDiff.Sub(*recMemory)
-			}
-
+			containerCpuDiff := QuantityDiff(containerCpu, recCpu)
+			containerMemoryDiff := QuantityDiff(containerMemory, recMemory)
 			row = append(row, PrintQuantity(containerCpuDiff))
 			row = append(row, PrintQuantity(containerMemoryDiff))
 			diffCpuTotal.Add(*containerCpuDiff)
 			diffMemoryTotal.Add(*containerMemoryDiff)
 
+			if o.PrintOptions.IsWide() {
+				owner := ""
+				if len(owners) > 0 {
+					owner = owners[0].Kind + "/" + owners[0].Name
+				}
+				row = append(row, pod.Spec.NodeName, owner)
+			}
+
 			t.AppendRows([]table.Row{
 				row,
 			})
@@ -207,8 +213,45 @@ func (o *CranePodOptions) Run() error {
 		t.AppendSeparator()
 	}
 
-	t.AppendFooter(table.Row{"Total", "", PrintQuantity(cpuTotal), PrintQuantity(memoryTotal), PrintQuantity(recCpuTotal), PrintQuantity(recMemoryTotal), PrintQuantity(diffCpuTotal), PrintQuantity(diffMemoryTotal)})
+	footer := table.Row{"Total"}
+	if o.AllNamespaces {
+		footer = append(footer, "")
+	}
+	footer = append(footer, table.Row{"", PrintQuantity(cpuTotal), PrintQuantity(memoryTotal), PrintQuantity(recCpuTotal), PrintQuantity(recMemoryTotal), PrintQuantity(diffCpuTotal), PrintQuantity(diffMemoryTotal)}...)
+	t.AppendFooter(footer)
 	t.Render()
 
 	return nil
 }
+
+// getWorkloadOwners returns the owners of the pod, the ReplicaSets owned by a Deployment
+// are replaced with the Deployment because the recommendations target the Deployment.
+func (o *CranePodOptions) getWorkloadOwners(pod corev1.Pod) []metav1.OwnerReference {
+	var owners []metav1.OwnerReference
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind != "ReplicaSet" {
+			owners = append(owners, ref)
+			continue
+		}
+
+		key := pod.Namespace + "/" + ref.Name
+		replicaSetOwners, exist := o.replicaSetOwners[key]
+		if !exist {
+			replicaSet, err := o.CommonOptions.KubeClient.AppsV1().ReplicaSets(pod.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+			if err != nil {
+				klog.Warningf("Failed to get the ReplicaSet %s, %v.", key, err)
+			} else {
+				replicaSetOwners = replicaSet.OwnerReferences
+			}
+			o.replicaSetOwners[key] = replicaSetOwners
+		}
+
+		if len(replicaSetOwners) > 0 {
+			owners = append(owners, replicaSetOwners...)
+		} else {
+			owners = append(owners, ref)
+		}
+	}
+
+	return owners
+}
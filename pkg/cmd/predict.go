@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/predict"
+)
+
+type PredictOptions struct {
+	CommonOptions *options.CommonOptions
+}
+
+func NewPredictOptions() *PredictOptions {
+	return &PredictOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdPredict() *cobra.Command {
+	predictOptions := NewPredictOptions()
+
+	cmd := &cobra.Command{
+		Use:   "predict",
+		Short: "view the time series predictions of workloads",
+	}
+	predictOptions.CommonOptions.AddCommonFlag(cmd)
+
+	cmd.AddCommand(predict.NewCmdPredictShow())
+
+	return cmd
+}
@@ -0,0 +1,189 @@
+package predict
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// sparklineWidth is the width of the trend in the summary table
+	sparklineWidth = 30
+	// chartTimeFormat is short enough to label both ends of the chart
+	chartTimeFormat = "01-02 15:04"
+)
+
+// blocks are the eighths of a cell, from empty to full
+var blocks = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+func renderCharts(series []PredictionSeries, width, height int, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"RESOURCE", "LABELS", "SAMPLES", "MIN", "AVG", "MAX", "REQUESTED", "MAX/REQUESTED", "TREND"})
+
+	for _, s := range series {
+		min, avg, max := stats(s.Samples)
+		requested, usage := "-", "-"
+		if s.Requested != nil {
+			requested = formatValue(s.Resource, *s.Requested)
+			if *s.Requested > 0 {
+				usage = fmt.Sprintf("%.0f%%", max / *s.Requested * 100)
+			}
+		}
+		t.AppendRow(table.Row{s.Resource, formatLabels(s.Labels), len(s.Samples),
+			formatValue(s.Resource, min), formatValue(s.Resource, avg), formatValue(s.Resource, max),
+			requested, usage, sparkline(resample(s.Samples, sparklineWidth))})
+	}
+	t.Render()
+
+	for _, s := range series {
+		title := fmt.Sprintf("%s of %s", s.Resource, s.Prediction)
+		if len(s.Labels) > 0 {
+			title += " {" + formatLabels(s.Labels) + "}"
+		}
+		fmt.Fprintf(out, "\n%s\n", title)
+		renderChart(s, width, height, out)
+	}
+}
+
+// renderChart draws the predicted values as columns of blocks with the requested value as a line across
+func renderChart(s PredictionSeries, width, height int, out io.Writer) {
+	values := resample(s.Samples, width)
+
+	top := 0.0
+	for _, value := range values {
+		top = math.Max(top, value)
+	}
+	if s.Requested != nil {
+		top = math.Max(top, *s.Requested)
+	}
+	if top <= 0 {
+		top = 1
+	}
+
+	requestedRow := -1
+	if s.Requested != nil {
+		requestedRow = int(*s.Requested / top * float64(height))
+		if requestedRow >= height {
+			requestedRow = height - 1
+		}
+	}
+
+	labels := make([]string, height)
+	labels[height-1] = formatValue(s.Resource, top)
+	labels[height/2] = formatValue(s.Resource, top*float64(height/2+1)/float64(height))
+	labels[0] = formatValue(s.Resource, top/float64(height))
+	labelWidth := 0
+	for _, label := range labels {
+		if len(label) > labelWidth {
+			labelWidth = len(label)
+		}
+	}
+
+	for row := height - 1; row >= 0; row-- {
+		var line strings.Builder
+		for _, value := range values {
+			eighths := int(math.Round(value/top*float64(height)*8)) - row*8
+			switch {
+			case eighths >= 8:
+				line.WriteRune(blocks[8])
+			case eighths > 0:
+				line.WriteRune(blocks[eighths])
+			case row == requestedRow:
+				line.WriteRune('─')
+			default:
+				line.WriteRune(' ')
+			}
+		}
+		if row == requestedRow {
+			line.WriteString(" requested " + formatValue(s.Resource, *s.Requested))
+		}
+		fmt.Fprintf(out, "%*s ┤%s\n", labelWidth, labels[row], line.String())
+	}
+
+	fmt.Fprintf(out, "%*s └%s\n", labelWidth, "", strings.Repeat("─", len(values)))
+	start := s.Samples[0].Timestamp.Format(chartTimeFormat)
+	end := s.Samples[len(s.Samples)-1].Timestamp.Format(chartTimeFormat)
+	if pad := len(values) - len(start); pad > len(end) {
+		fmt.Fprintf(out, "%*s  %s%*s\n", labelWidth, "", start, pad, end)
+	} else {
+		// too few columns to label both ends apart
+		fmt.Fprintf(out, "%*s  %s - %s\n", labelWidth, "", start, end)
+	}
+}
+
+// resample squeezes the samples into at most width values, keeping the peak of each bucket
+func resample(samples []PredictedSample, width int) []float64 {
+	if len(samples) <= width {
+		values := make([]float64, len(samples))
+		for i, sample := range samples {
+			values[i] = sample.Value
+		}
+		return values
+	}
+
+	values := make([]float64, width)
+	for i := range values {
+		from, to := i*len(samples)/width, (i+1)*len(samples)/width
+		values[i] = samples[from].Value
+		for _, sample := range samples[from:to] {
+			values[i] = math.Max(values[i], sample.Value)
+		}
+	}
+
+	return values
+}
+
+// sparkline draws the values between their min and max in a single line
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	min, max := values[0], values[0]
+	for _, value := range values {
+		min, max = math.Min(min, value), math.Max(max, value)
+	}
+
+	var line strings.Builder
+	for _, value := range values {
+		level := 1
+		if max > min {
+			level = 1 + int(math.Round((value-min)/(max-min)*7))
+		}
+		line.WriteRune(blocks[level])
+	}
+
+	return line.String()
+}
+
+func stats(samples []PredictedSample) (float64, float64, float64) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+
+	min, max, sum := samples[0].Value, samples[0].Value, 0.0
+	for _, sample := range samples {
+		min, max = math.Min(min, sample.Value), math.Max(max, sample.Value)
+		sum += sample.Value
+	}
+
+	return min, sum / float64(len(samples)), max
+}
+
+// formatValue prints cpu in millicores and memory in Mi, the units the requests are usually written in
+func formatValue(resource string, value float64) string {
+	switch resource {
+	case string(corev1.ResourceCPU):
+		return fmt.Sprintf("%.0fm", value*1000)
+	case string(corev1.ResourceMemory):
+		return fmt.Sprintf("%.0fMi", value/(1<<20))
+	}
+
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,349 @@
+package predict
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	predictShowExample = `
+# chart the predicted cpu and memory of deployment nginx against its requests
+%[1]s predict show nginx -n default
+
+# chart the predicted cpu of a statefulset in a wider chart
+%[1]s predict show statefulset/redis -n default --resource cpu --width 120 --height 15
+
+# export the predicted samples as csv
+%[1]s predict show nginx -n default --format csv > nginx.csv
+`
+)
+
+const (
+	PredictFormatChart = "chart"
+	PredictFormatCSV   = "csv"
+	PredictFormatJSON  = "json"
+)
+
+// PredictionSeries is a predicted time series of a workload with the resources requested by the workload
+type PredictionSeries struct {
+	Prediction string `json:"prediction"`
+	// Resource is cpu or memory, or the resource identifier for the metrics which aren't resource queries
+	Resource string            `json:"resource"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Samples  []PredictedSample `json:"samples"`
+	// Requested sums the requests of all the replicas, cpu in cores and memory in bytes, nil when unknown
+	Requested *float64 `json:"requested,omitempty"`
+}
+
+type PredictedSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+type PredictShowOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Kind     string
+	Name     string
+	Resource string
+	Format   string
+	Width    int
+	Height   int
+}
+
+func NewPredictShowOptions() *PredictShowOptions {
+	return &PredictShowOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdPredictShow() *cobra.Command {
+	o := NewPredictShowOptions()
+
+	command := &cobra.Command{
+		Use:     "show [<kind>/]<workload>",
+		Short:   "chart the predicted usage of a workload against its requests",
+		Example: fmt.Sprintf(predictShowExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+predictShowExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *PredictShowOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the workload name")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+		return errors.New("please specify the workload namespace")
+	}
+
+	switch o.Format {
+	case PredictFormatChart, PredictFormatCSV, PredictFormatJSON:
+	default:
+		return fmt.Errorf("unsupported format %s, please specify one of %s, %s, %s", o.Format, PredictFormatChart, PredictFormatCSV, PredictFormatJSON)
+	}
+
+	if o.Width < 10 {
+		return errors.New("the chart width should be at least 10")
+	}
+
+	if o.Height < 2 {
+		return errors.New("the chart height should be at least 2")
+	}
+
+	return nil
+}
+
+func (o *PredictShowOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Kind = "Deployment"
+		o.Name = args[0]
+		if i := strings.Index(args[0], "/"); i >= 0 {
+			o.Kind = o.resolveKind(args[0][:i])
+			o.Name = args[0][i+1:]
+		}
+	}
+
+	return nil
+}
+
+// resolveKind turns a resource or a short name like deploy or sts into its kind
+func (o *PredictShowOptions) resolveKind(kind string) string {
+	if o.CommonOptions.RestMapper != nil {
+		if gvk, err := o.CommonOptions.RestMapper.KindFor(schema.GroupVersionResource{Resource: strings.ToLower(kind)}); err == nil {
+			return gvk.Kind
+		}
+	}
+
+	return kind
+}
+
+func (o *PredictShowOptions) Run() error {
+	namespace := *o.CommonOptions.ConfigFlags.Namespace
+
+	predictionList, err := o.CommonOptions.CraneClient.PredictionV1alpha1().TimeSeriesPredictions(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get time series predictions, %v.", err)
+		return err
+	}
+
+	var series []PredictionSeries
+	found := false
+	for i := range predictionList.Items {
+		prediction := &predictionList.Items[i]
+		targetRef := prediction.Spec.TargetRef
+		if targetRef.Name != o.Name || !strings.EqualFold(targetRef.Kind, o.Kind) {
+			continue
+		}
+		found = true
+
+		series = append(series, o.buildSeries(prediction)...)
+	}
+
+	if !found {
+		return fmt.Errorf("no time series prediction found for %s %s/%s", o.Kind, namespace, o.Name)
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("the time series prediction for %s %s/%s has no predicted samples yet", o.Kind, namespace, o.Name)
+	}
+
+	switch o.Format {
+	case PredictFormatCSV:
+		return renderCSV(series, o.CommonOptions.Out)
+	case PredictFormatJSON:
+		return renderJSON(series, o.CommonOptions.Out)
+	default:
+		renderCharts(series, o.Width, o.Height, o.CommonOptions.Out)
+	}
+
+	return nil
+}
+
+// buildSeries parses the predicted samples of the prediction and looks up what the workload requests
+func (o *PredictShowOptions) buildSeries(prediction *predictionapi.TimeSeriesPrediction) []PredictionSeries {
+	resources := map[string]string{}
+	for _, metric := range prediction.Spec.PredictionMetrics {
+		if metric.ResourceQuery != nil {
+			resources[metric.ResourceIdentifier] = string(*metric.ResourceQuery)
+		}
+	}
+
+	requested := o.requested(prediction.Spec.TargetRef)
+
+	var series []PredictionSeries
+	for _, metric := range prediction.Status.PredictionMetrics {
+		resource, exist := resources[metric.ResourceIdentifier]
+		if !exist {
+			resource = metric.ResourceIdentifier
+		}
+		if len(o.Resource) > 0 && resource != o.Resource {
+			continue
+		}
+
+		for _, timeSeries := range metric.Prediction {
+			if timeSeries == nil {
+				continue
+			}
+
+			s := PredictionSeries{
+				Prediction: prediction.Name,
+				Resource:   resource,
+				Requested:  requested[resource],
+			}
+			if len(timeSeries.Labels) > 0 {
+				s.Labels = map[string]string{}
+				for _, label := range timeSeries.Labels {
+					s.Labels[label.Name] = label.Value
+				}
+			}
+			for _, sample := range timeSeries.Samples {
+				value, err := strconv.ParseFloat(sample.Value, 64)
+				if err != nil {
+					klog.Warningf("Skip the invalid sample %q of time series prediction %s/%s, %v.", sample.Value, prediction.Namespace, prediction.Name, err)
+					continue
+				}
+				s.Samples = append(s.Samples, PredictedSample{Timestamp: time.Unix(sample.Timestamp, 0), Value: value})
+			}
+			if len(s.Samples) == 0 {
+				continue
+			}
+			sort.Slice(s.Samples, func(i, j int) bool {
+				return s.Samples[i].Timestamp.Before(s.Samples[j].Timestamp)
+			})
+
+			series = append(series, s)
+		}
+	}
+
+	return series
+}
+
+// requested returns the cpu cores and memory bytes requested by all the replicas of the workload
+func (o *PredictShowOptions) requested(targetRef corev1.ObjectReference) map[string]*float64 {
+	requested := map[string]*float64{}
+
+	gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		klog.Warningf("Failed to get the resource of %s %s, %v.", targetRef.APIVersion, targetRef.Kind, err)
+		return requested
+	}
+	live, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("Failed to get %s %s/%s, the requests are not charted, %v.", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+		return requested
+	}
+
+	cpu, memory, err := utils.WorkloadRequests(live)
+	if err != nil {
+		klog.Warningf("Failed to get the requests of %s %s/%s, %v.", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+		return requested
+	}
+
+	replicas := float64(utils.WorkloadReplicas(live))
+	if !cpu.IsZero() {
+		cpuCores := float64(cpu.MilliValue()) / 1000 * replicas
+		requested[string(corev1.ResourceCPU)] = &cpuCores
+	}
+	if !memory.IsZero() {
+		memoryBytes := float64(memory.Value()) * replicas
+		requested[string(corev1.ResourceMemory)] = &memoryBytes
+	}
+
+	return requested
+}
+
+func renderCSV(series []PredictionSeries, out io.Writer) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"prediction", "resource", "labels", "timestamp", "predicted", "requested"}); err != nil {
+		return err
+	}
+
+	for _, s := range series {
+		requested := ""
+		if s.Requested != nil {
+			requested = strconv.FormatFloat(*s.Requested, 'f', -1, 64)
+		}
+		for _, sample := range s.Samples {
+			record := []string{s.Prediction, s.Resource, formatLabels(s.Labels), sample.Timestamp.UTC().Format(time.RFC3339),
+				strconv.FormatFloat(sample.Value, 'f', -1, 64), requested}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func renderJSON(series []PredictionSeries, out io.Writer) error {
+	data, err := json.MarshalIndent(series, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (o *PredictShowOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Resource, "resource", "", "", "Only show the prediction of the resource, e.g. cpu or memory")
+	cmd.Flags().StringVarP(&o.Format, "format", "", PredictFormatChart, "Specify the output format[chart, csv, json]")
+	cmd.Flags().IntVarP(&o.Width, "width", "", 60, "The width of the chart in columns")
+	cmd.Flags().IntVarP(&o.Height, "height", "", 10, "The height of the chart in rows")
+}
@@ -28,6 +28,10 @@ func NewCmdRecommend() *cobra.Command {
 
 	cmd.AddCommand(recommend.NewCmdRecommendList())
 	cmd.AddCommand(recommend.NewCmdRecommendAdopt())
+	cmd.AddCommand(recommend.NewCmdRecommendDiff())
+	cmd.AddCommand(recommend.NewCmdRecommendExport())
+	cmd.AddCommand(recommend.NewCmdRecommendNodes())
+	cmd.AddCommand(recommend.NewCmdRecommendRollback())
 	cmd.AddCommand(recommend.NewCmdRecommendTrigger())
 
 	return cmd
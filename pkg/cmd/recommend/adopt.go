@@ -4,13 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
 
 	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
 	"github.com/gocrane/kubectl-crane/pkg/utils"
@@ -23,19 +33,77 @@ var (
 
 # pre-commit
 %[1]s recommend adopt --name workloads-rule-resource-ntzns --dry-run
+
+# show the change to the target before adopting it
+%[1]s recommend adopt --name workloads-rule-resource-ntzns --diff --dry-run
+
+# adopt all Resource recommendations in kube-system namespace
+%[1]s recommend adopt --namespace kube-system --type Resource
+
+# adopt all recommendations created by a recommendation rule across all namespaces
+%[1]s recommend adopt --ruleName workloads-rule -A
+
+# refuse the recommendations decreasing the memory request by more than 30%%
+%[1]s recommend adopt --namespace kube-system --type Resource --max-memory-decrease 30
+
+# write the recommendations into the manifests of a gitops repository instead of the cluster
+%[1]s recommend adopt --namespace kube-system --type Resource --to-manifests ./deploy --diff
+
+# clamp the recommendations into the guardrails defined in a config file
+%[1]s recommend adopt --namespace kube-system --guardrails-config guardrails.yaml --guardrail-action clamp
+
+# update the HPA or EHPA scaling the target with the Replicas recommendation, or create an EHPA previewing the replicas if there is none
+%[1]s recommend adopt --namespace default --type Replicas --create-ehpa
+
+# create the EHPA scaling the target right away
+%[1]s recommend adopt --name workloads-rule-replicas-p84jv --namespace default --create-ehpa --ehpa-strategy Auto
+
+# cordon the idle node and evict its pods, respecting the PodDisruptionBudgets
+%[1]s recommend adopt --name idlenodes-rule-idlenode-5dx8k -n crane-system --drain-timeout 10m
+
+# cordon and drain all the idle nodes, the idle nodes are skipped in bulk adoption without --type IdleNode --yes
+%[1]s recommend adopt -n crane-system --type IdleNode --yes
 `
 )
 
+const (
+	AdoptResultPatched = "Patched"
+	AdoptResultSkipped = "Skipped"
+	AdoptResultFailed  = "Failed"
+	AdoptResultRefused = "Refused"
+)
+
+// AdoptResult records the outcome of adopting a single recommendation
+type AdoptResult struct {
+	Recommendation analysisv1alpha1.Recommendation
+	Result         string
+	Message        string
+}
+
 type RecommendAdoptOptions struct {
-	CommonOptions *options.CommonOptions
+	CommonOptions    *options.CommonOptions
+	FilterOptions    *RecommendFilterOptions
+	GuardrailOptions *GuardrailOptions
+
+	DryRun       bool
+	Diff         bool
+	NoColor      bool
+	Name         string
+	ToManifests  string
+	Force        bool
+	DrainTimeout time.Duration
+	CreateEHPA   bool
+	EHPAStrategy string
+	Yes          bool
 
-	DryRun bool
-	Name   string
+	manifestWriter *ManifestWriter
 }
 
 func NewRecommendAdoptOptions() *RecommendAdoptOptions {
 	return &RecommendAdoptOptions{
-		CommonOptions: options.NewCommonOptions(),
+		CommonOptions:    options.NewCommonOptions(),
+		FilterOptions:    NewRecommendFilterOptions(),
+		GuardrailOptions: NewGuardrailOptions(),
 	}
 }
 
@@ -45,7 +113,7 @@ func NewCmdRecommendAdopt() *cobra.Command {
 	command := &cobra.Command{
 		Use:     "adopt",
 		Short:   "Adopt a recommend to resource",
-		Example: fmt.Sprintf(recommendListExample, "kubectl-crane"),
+		Example: fmt.Sprintf(recommendAdoptExample, "kubectl-crane"),
 		RunE: func(c *cobra.Command, args []string) error {
 			if err := o.Complete(c, args); err != nil {
 				return err
@@ -74,14 +142,34 @@ func (o *RecommendAdoptOptions) Validate() error {
 		return err
 	}
 
-	if len(o.Name) == 0 {
-		return errors.New("please specify the recommend name")
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.GuardrailOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 && o.FilterOptions.IsEmpty() {
+		return errors.New("please specify the recommend name or the selectors of recommendations")
+	}
+
+	if len(o.Name) > 0 && (!o.FilterOptions.IsEmpty() || o.FilterOptions.AllNamespaces) {
+		return errors.New("the recommend name can't be used together with the selectors of recommendations")
 	}
 
-	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 && !o.FilterOptions.AllNamespaces {
 		return errors.New("please specify the recommend namespace")
 	}
 
+	if o.EHPAStrategy != string(autoscalingapi.ScaleStrategyAuto) && o.EHPAStrategy != string(autoscalingapi.ScaleStrategyPreview) {
+		return fmt.Errorf("unsupported scale strategy %s, please specify one of %s, %s", o.EHPAStrategy, autoscalingapi.ScaleStrategyAuto, autoscalingapi.ScaleStrategyPreview)
+	}
+
+	if o.Yes && o.FilterOptions.Type != analysisv1alpha1.IdleNodeRecommender {
+		return errors.New("please specify --type IdleNode with --yes, it only confirms draining the idle nodes")
+	}
+
 	return nil
 }
 
@@ -90,48 +178,371 @@ func (o *RecommendAdoptOptions) Complete(cmd *cobra.Command, args []string) erro
 		return err
 	}
 
+	if err := o.GuardrailOptions.Complete(cmd); err != nil {
+		return err
+	}
+
+	if len(o.ToManifests) > 0 {
+		writer, err := NewManifestWriter(o.ToManifests)
+		if err != nil {
+			return err
+		}
+		o.manifestWriter = writer
+	}
+
 	return nil
 }
 
 func (o *RecommendAdoptOptions) Run() error {
-	recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if len(o.Name) > 0 {
+		recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+		}
+
+		message, err := o.adopt(recommend)
+		if errors.Is(err, ErrAlreadyAdopted) {
+			klog.Infof("%s/%s: %v", recommend.Spec.TargetRef.Namespace, recommend.Spec.TargetRef.Name, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(message) > 0 {
+			klog.Warningf("%s", message)
+		}
+
+		return o.flushManifests()
+	}
+
+	recommendations, err := o.FilterOptions.ListRecommendations(o.CommonOptions)
 	if err != nil {
-		return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+		return err
 	}
 
-	if string(recommend.Spec.Type) == "Replicas" ||
-		string(recommend.Spec.Type) == "Resource" {
-		gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, recommend.Spec.TargetRef.APIVersion, recommend.Spec.TargetRef.Kind)
-		if err != nil {
-			return fmt.Errorf("recommendation type %s is not supported for adoption", string(recommend.Spec.Type))
+	// adopt the recommendations one by one and continue past the failed ones
+	var results []AdoptResult
+	failed := 0
+	for i := range recommendations {
+		recommend := &recommendations[i]
+		result := AdoptResult{Recommendation: *recommend, Result: AdoptResultPatched}
+		idleNode := string(recommend.Spec.Type) == analysisv1alpha1.IdleNodeRecommender
+		if idleNode && !o.confirmIdleNodes() {
+			result.Result = AdoptResultSkipped
+			result.Message = "the idle node is only drained with --name, or with --type IdleNode --yes"
+		} else if !idleNode && !isAdoptable(recommend) {
+			result.Result = AdoptResultSkipped
+			result.Message = fmt.Sprintf("recommendation type %s is not supported for adoption", string(recommend.Spec.Type))
+		} else if !idleNode && len(recommend.Status.RecommendedInfo) == 0 {
+			result.Result = AdoptResultSkipped
+			result.Message = "the recommendation has no recommended value yet"
+		} else if message, err := o.adopt(recommend); err != nil {
+			var guardrailError *GuardrailError
+			if errors.Is(err, ErrAlreadyAdopted) {
+				result.Result = AdoptResultSkipped
+			} else if errors.As(err, &guardrailError) {
+				result.Result = AdoptResultRefused
+			} else {
+				result.Result = AdoptResultFailed
+				failed++
+			}
+			result.Message = err.Error()
+		} else {
+			result.Message = message
 		}
+		results = append(results, result)
+	}
+
+	renderAdoptResults(results, o.CommonOptions.Out)
 
-		patchOptions := metav1.PatchOptions{}
-		if o.DryRun {
-			patchOptions.DryRun = []string{"All"}
+	if err := o.flushManifests(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to adopt %d of %d recommendations", failed, len(results))
+	}
+
+	return nil
+}
+
+// adopt patches the target with the recommendation, the returned message
+// describes the values clamped by the guardrails.
+func (o *RecommendAdoptOptions) adopt(recommend *analysisv1alpha1.Recommendation) (string, error) {
+	// the idle nodes are drained instead of patched
+	if string(recommend.Spec.Type) == analysisv1alpha1.IdleNodeRecommender {
+		if o.manifestWriter != nil {
+			return "", errors.New("the idle nodes can't be adopted into manifests")
 		}
+		return o.adoptIdleNode(recommend)
+	}
 
-		patched, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(recommend.Spec.TargetRef.Namespace).Patch(context.TODO(), recommend.Spec.TargetRef.Name, types.StrategicMergePatchType, []byte(recommend.Status.RecommendedInfo), patchOptions)
-		if err != nil {
-			return fmt.Errorf("adopt the recommend failed because %v", err)
+	if !isAdoptable(recommend) {
+		return "", fmt.Errorf("recommendation type %s is not supported for adoption ", string(recommend.Spec.Type))
+	}
+
+	if o.manifestWriter != nil {
+		return o.adoptToManifests(recommend)
+	}
+
+	// the replicas owned by an autoscaler are overwritten, adopt the recommendation into the autoscaler
+	if string(recommend.Spec.Type) == analysisv1alpha1.ReplicasRecommender {
+		if adopted, message, err := o.adoptToAutoscaler(recommend); adopted {
+			return message, err
+		}
+	}
+
+	gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, recommend.Spec.TargetRef.APIVersion, recommend.Spec.TargetRef.Kind)
+	if err != nil {
+		return "", fmt.Errorf("recommendation type %s is not supported for adoption", string(recommend.Spec.Type))
+	}
+
+	live, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(recommend.Spec.TargetRef.Namespace).Get(context.TODO(), recommend.Spec.TargetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("adopt the recommend failed because %v", err)
+	}
+
+	recommendedInfo, violations, err := o.GuardrailOptions.Apply(live, recommend)
+	if err != nil {
+		return "", err
+	}
+	message := ""
+	if len(violations) > 0 {
+		message = "clamped by guardrails: " + strings.Join(violations, "; ")
+	}
+	recommend = recommend.DeepCopy()
+	recommend.Status.RecommendedInfo = recommendedInfo
+
+	if o.Diff {
+		if err := DiffRecommendation(o.CommonOptions, recommend, false, colorEnabled(o.CommonOptions.Out, o.NoColor), o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+	}
+
+	// record the overwritten values on the target so that the adoption can be rolled back
+	patch, err := buildAdoptPatch(live, recommend)
+	if errors.Is(err, ErrAlreadyAdopted) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("adopt the recommend failed because %v", err)
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if o.DryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+
+	patched, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(recommend.Spec.TargetRef.Namespace).Patch(context.TODO(), recommend.Spec.TargetRef.Name, types.StrategicMergePatchType, patch, patchOptions)
+	if err != nil {
+		return "", fmt.Errorf("adopt the recommend failed because %v", err)
+	}
+
+	// when dry-run set, print the object unless the diff has been printed
+	if o.DryRun && !o.Diff {
+		printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		if err = printer.PrintObj(patched, o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+	}
+
+	return message, nil
+}
+
+// adoptIdleNode cordons the idle node and drains it, the node itself is left to be removed
+// by the cluster autoscaler or the node pool.
+func (o *RecommendAdoptOptions) adoptIdleNode(recommend *analysisv1alpha1.Recommendation) (string, error) {
+	nodeName := recommend.Spec.TargetRef.Name
+
+	// check the pods before cordoning, so that a refused node is left untouched
+	pods, err := ListNodePods(o.CommonOptions, nodeName)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckUnmanagedPods(PodsToEvict(pods), o.Force); err != nil {
+		return "", err
+	}
+
+	if o.Diff {
+		if err := o.diffIdleNode(nodeName, PodsToEvict(pods)); err != nil {
+			return "", err
+		}
+	}
+
+	node, err := CordonNode(o.CommonOptions, nodeName, o.DryRun)
+	if err != nil {
+		return "", fmt.Errorf("cordon the node %s failed because %v", nodeName, err)
+	}
+
+	pods, err = DrainNode(o.CommonOptions, nodeName, o.Force, o.DrainTimeout, o.DryRun)
+	if err != nil {
+		return "", err
+	}
+
+	if o.DryRun {
+		node.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+		printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		if err = printer.PrintObj(node, o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+
+		var names []string
+		for _, pod := range pods {
+			names = append(names, pod.Namespace+"/"+pod.Name)
 		}
+		return fmt.Sprintf("would cordon the node %s and evict %d pods %s", nodeName, len(pods), strings.Join(names, ",")), nil
+	}
+
+	return fmt.Sprintf("cordoned the node %s and evicted %d pods", nodeName, len(pods)), nil
+}
+
+// confirmIdleNodes tells whether the idle nodes selected in bulk may be cordoned and drained,
+// they are only when selected by type explicitly and confirmed with --yes.
+func (o *RecommendAdoptOptions) confirmIdleNodes() bool {
+	return o.FilterOptions.Type == analysisv1alpha1.IdleNodeRecommender && o.Yes
+}
+
+// diffIdleNode prints the diff of cordoning the node and the pods to evict from it
+func (o *RecommendAdoptOptions) diffIdleNode(nodeName string, pods []corev1.Pod) error {
+	node, err := o.CommonOptions.KubeClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the node %s, %v", nodeName, err)
+	}
+
+	cordoned := node.Spec.DeepCopy()
+	cordoned.Unschedulable = true
+	from, err := yaml.Marshal(map[string]interface{}{"spec": node.Spec})
+	if err != nil {
+		return err
+	}
+	to, err := yaml.Marshal(map[string]interface{}{"spec": cordoned})
+	if err != nil {
+		return err
+	}
 
-		// when dry-run set, print the object
-		if o.DryRun {
-			printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
-			if err = printer.PrintObj(patched, o.CommonOptions.Out); err != nil {
+	name := "node/" + nodeName
+	changed, err := printUnifiedDiff(from, to, name+" (live)", name+" (cordoned)", colorEnabled(o.CommonOptions.Out, o.NoColor), o.CommonOptions.Out)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Fprintf(o.CommonOptions.Out, "%s is already cordoned\n", name)
+	}
+
+	for _, pod := range pods {
+		fmt.Fprintf(o.CommonOptions.Out, "evict pod %s/%s\n", pod.Namespace, pod.Name)
+	}
+
+	return nil
+}
+
+// adoptToManifests writes the recommendation into the local manifests of the target,
+// the manifests stand for the live object when checking the guardrails.
+func (o *RecommendAdoptOptions) adoptToManifests(recommend *analysisv1alpha1.Recommendation) (string, error) {
+	current, err := o.manifestWriter.Current(recommend.Spec.TargetRef)
+	if err != nil {
+		return "", err
+	}
+
+	recommendedInfo, violations, err := o.GuardrailOptions.Apply(current, recommend)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := o.manifestWriter.Adopt(recommend, recommendedInfo)
+	if err != nil {
+		return "", err
+	}
+
+	message := "updated " + strings.Join(files, ",")
+	if len(violations) > 0 {
+		message += ", clamped by guardrails: " + strings.Join(violations, "; ")
+	}
+
+	return message, nil
+}
+
+// flushManifests prints the diff of the changed manifests and writes them unless dry-run is set
+func (o *RecommendAdoptOptions) flushManifests() error {
+	if o.manifestWriter == nil {
+		return nil
+	}
+
+	if o.Diff || o.DryRun {
+		changes, err := o.manifestWriter.Changes()
+		if err != nil {
+			return err
+		}
+		var paths []string
+		for path := range changes {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if _, err := printUnifiedDiff(changes[path][0], changes[path][1], path, path, colorEnabled(o.CommonOptions.Out, o.NoColor), o.CommonOptions.Out); err != nil {
 				return err
 			}
 		}
+	}
 
-	} else {
-		return fmt.Errorf("recommendation type %s is not supported for adoption ", string(recommend.Spec.Type))
+	if o.DryRun {
+		return nil
+	}
+
+	paths, err := o.manifestWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		klog.Infof("updated manifest %s", path)
 	}
 
 	return nil
 }
 
+func isAdoptable(recommend *analysisv1alpha1.Recommendation) bool {
+	return string(recommend.Spec.Type) == "Replicas" ||
+		string(recommend.Spec.Type) == "Resource"
+}
+
+func renderAdoptResults(results []AdoptResult, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"NAME", "NAMESPACE", "TYPE", "TARGET KIND", "TARGET NAME", "RESULT", "MESSAGE"})
+
+	summary := map[string]int{}
+	for _, result := range results {
+		t.AppendRow(table.Row{
+			result.Recommendation.Name,
+			result.Recommendation.Namespace,
+			result.Recommendation.Spec.Type,
+			result.Recommendation.Spec.TargetRef.Kind,
+			result.Recommendation.Spec.TargetRef.Name,
+			result.Result,
+			result.Message,
+		})
+		summary[result.Result]++
+	}
+
+	t.AppendFooter(table.Row{"Total", len(results), "", "", "",
+		fmt.Sprintf("%s: %d, %s: %d, %s: %d, %s: %d", AdoptResultPatched, summary[AdoptResultPatched], AdoptResultSkipped, summary[AdoptResultSkipped],
+			AdoptResultRefused, summary[AdoptResultRefused], AdoptResultFailed, summary[AdoptResultFailed])})
+	t.Render()
+}
+
 func (o *RecommendAdoptOptions) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for recommend")
 	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+	cmd.Flags().BoolVarP(&o.Diff, "diff", "", false, "Print the diff of the target before adopting the recommend")
+	cmd.Flags().BoolVarP(&o.NoColor, "no-color", "", false, "Print the diff without color, the diff is only colored on a terminal")
+	cmd.Flags().StringVarP(&o.ToManifests, "to-manifests", "", "", "Write the recommend into the yaml manifests or kustomize overlays under the directory instead of the cluster")
+	cmd.Flags().BoolVarP(&o.CreateEHPA, "create-ehpa", "", false, "Create an EffectiveHorizontalPodAutoscaler from the proposed spec when the target of a Replicas recommendation has no HPA or EHPA")
+	cmd.Flags().StringVarP(&o.EHPAStrategy, "ehpa-strategy", "", string(autoscalingapi.ScaleStrategyPreview), "Specify the scale strategy[Auto, Preview] of the EffectiveHorizontalPodAutoscaler created with --create-ehpa, the Preview strategy doesn't scale the target")
+	cmd.Flags().BoolVarP(&o.Force, "force", "", false, "Evict the pods not managed by a controller when draining an idle node")
+	cmd.Flags().DurationVarP(&o.DrainTimeout, "drain-timeout", "", DefaultDrainTimeout, "The time to wait for the pods of each idle node to be evicted, the idle nodes are drained one after another")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "", false, "Confirm cordoning and draining the idle nodes selected with --type IdleNode")
+	o.FilterOptions.AddFlags(cmd)
+	o.GuardrailOptions.AddFlags(cmd)
 }
@@ -0,0 +1,497 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+	autoscalingapi "github.com/gocrane/api/autoscaling/v1alpha1"
+	cranescheme "github.com/gocrane/api/pkg/generated/clientset/versioned/scheme"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+const (
+	AutoscalerKindHPA  = "HorizontalPodAutoscaler"
+	AutoscalerKindEHPA = "EffectiveHorizontalPodAutoscaler"
+)
+
+// hpaVersions are the versions of the HPA tried in order, autoscaling/v2 isn't served before 1.23
+var hpaVersions = []schema.GroupVersion{
+	autoscalingv2.SchemeGroupVersion,
+	autoscalingv2beta2.SchemeGroupVersion,
+	autoscalingv1.SchemeGroupVersion,
+}
+
+// Autoscaler is the HPA or the EHPA owning the replicas of a workload
+type Autoscaler struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// APIVersion is the version the autoscaler is served with, the HPA may be served with an older version
+	APIVersion string
+}
+
+func (a *Autoscaler) String() string {
+	return fmt.Sprintf("%s %s/%s", a.Kind, a.Namespace, a.Name)
+}
+
+// GroupVersionResource returns the resource the autoscaler is read and patched with
+func (a *Autoscaler) GroupVersionResource() schema.GroupVersionResource {
+	if a.Kind == AutoscalerKindEHPA {
+		return autoscalingapi.SchemeGroupVersion.WithResource("effectivehorizontalpodautoscalers")
+	}
+
+	gv, err := schema.ParseGroupVersion(a.APIVersion)
+	if err != nil || len(a.APIVersion) == 0 {
+		gv = autoscalingv2.SchemeGroupVersion
+	}
+	return gv.WithResource("horizontalpodautoscalers")
+}
+
+// FindAutoscaler returns the EHPA or the HPA scaling the target, or nil if the target has none.
+// The HPA created by an EHPA is skipped since the EHPA overwrites it. The EHPA is skipped when its CRD
+// isn't installed, and the HPA is looked for with the older versions when autoscaling/v2 isn't served.
+// Failing to list the autoscalers, e.g. forbidden, is an error since the replicas patched on a target
+// scaled by an autoscaler are overwritten at once.
+func FindAutoscaler(commonOptions *options.CommonOptions, targetRef corev1.ObjectReference) (*Autoscaler, error) {
+	ehpaList, err := commonOptions.CraneClient.AutoscalingV1alpha1().EffectiveHorizontalPodAutoscalers(targetRef.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if isNotServed(err) {
+		klog.V(4).Infof("the %s is not served, %v", AutoscalerKindEHPA, err)
+		ehpaList = &autoscalingapi.EffectiveHorizontalPodAutoscalerList{}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list the %s in %s, %v", AutoscalerKindEHPA, targetRef.Namespace, err)
+	}
+	for _, ehpa := range ehpaList.Items {
+		if matchScaleTarget(ehpa.Spec.ScaleTargetRef.Kind, ehpa.Spec.ScaleTargetRef.Name, ehpa.Spec.ScaleTargetRef.APIVersion, targetRef) {
+			return &Autoscaler{Kind: AutoscalerKindEHPA, Namespace: ehpa.Namespace, Name: ehpa.Name, APIVersion: autoscalingapi.SchemeGroupVersion.String()}, nil
+		}
+	}
+
+	for _, gv := range hpaVersions {
+		hpaList, err := listHorizontalPodAutoscalers(commonOptions, gv, targetRef.Namespace)
+		if isNotServed(err) {
+			klog.V(4).Infof("the %s %s is not served, %v", gv, AutoscalerKindHPA, err)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to list the %s in %s, %v", AutoscalerKindHPA, targetRef.Namespace, err)
+		}
+
+		for _, hpa := range hpaList {
+			if controller := metav1.GetControllerOf(&hpa); controller != nil && controller.Kind == AutoscalerKindEHPA {
+				continue
+			}
+			if matchScaleTarget(hpa.ScaleTargetRef.Kind, hpa.ScaleTargetRef.Name, hpa.ScaleTargetRef.APIVersion, targetRef) {
+				return &Autoscaler{Kind: AutoscalerKindHPA, Namespace: hpa.Namespace, Name: hpa.Name, APIVersion: gv.String()}, nil
+			}
+		}
+		return nil, nil
+	}
+
+	return nil, nil
+}
+
+// scalingHPA is the part of an HPA of any version looked at to find the autoscaler of a target
+type scalingHPA struct {
+	metav1.ObjectMeta
+	ScaleTargetRef autoscalingv1.CrossVersionObjectReference
+}
+
+// listHorizontalPodAutoscalers lists the HPAs with the given version of autoscaling
+func listHorizontalPodAutoscalers(commonOptions *options.CommonOptions, gv schema.GroupVersion, namespace string) ([]scalingHPA, error) {
+	var hpas []scalingHPA
+	switch gv {
+	case autoscalingv2.SchemeGroupVersion:
+		hpaList, err := commonOptions.KubeClient.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, hpa := range hpaList.Items {
+			ref := hpa.Spec.ScaleTargetRef
+			hpas = append(hpas, scalingHPA{ObjectMeta: hpa.ObjectMeta, ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: ref.Kind, Name: ref.Name, APIVersion: ref.APIVersion}})
+		}
+	case autoscalingv2beta2.SchemeGroupVersion:
+		hpaList, err := commonOptions.KubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, hpa := range hpaList.Items {
+			ref := hpa.Spec.ScaleTargetRef
+			hpas = append(hpas, scalingHPA{ObjectMeta: hpa.ObjectMeta, ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: ref.Kind, Name: ref.Name, APIVersion: ref.APIVersion}})
+		}
+	case autoscalingv1.SchemeGroupVersion:
+		hpaList, err := commonOptions.KubeClient.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, hpa := range hpaList.Items {
+			hpas = append(hpas, scalingHPA{ObjectMeta: hpa.ObjectMeta, ScaleTargetRef: hpa.Spec.ScaleTargetRef})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version %s of the %s", gv, AutoscalerKindHPA)
+	}
+
+	return hpas, nil
+}
+
+// isNotServed tells whether the failed list means the resource isn't served by the cluster,
+// e.g. the EHPA CRD isn't installed or autoscaling/v2 isn't served before 1.23
+func isNotServed(err error) bool {
+	return err != nil && (apierrors.IsNotFound(err) || meta.IsNoMatchError(err))
+}
+
+// matchScaleTarget compares the group of the api versions only, e.g. apps/v1 matches apps/v1beta2
+func matchScaleTarget(kind, name, apiVersion string, targetRef corev1.ObjectReference) bool {
+	if kind != targetRef.Kind || name != targetRef.Name {
+		return false
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return false
+	}
+	targetGV, err := schema.ParseGroupVersion(targetRef.APIVersion)
+	if err != nil {
+		return false
+	}
+
+	return gv.Group == targetGV.Group
+}
+
+// ProposedAutoscaling decodes the EHPA spec proposed by a Replicas recommendation
+func ProposedAutoscaling(recommend *analysisv1alpha1.Recommendation) (*analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation, error) {
+	if len(recommend.Status.RecommendedValue) == 0 {
+		return nil, errors.New("the recommendation has no recommended value yet")
+	}
+
+	var proposed analysisv1alpha1.ProposedRecommendation
+	if err := yaml.Unmarshal([]byte(recommend.Status.RecommendedValue), &proposed); err != nil {
+		return nil, fmt.Errorf("invalid recommended value, %v", err)
+	}
+	if proposed.EffectiveHPA == nil {
+		return nil, errors.New("the recommendation proposes no autoscaling spec")
+	}
+
+	return proposed.EffectiveHPA, nil
+}
+
+// buildAutoscalerPatch returns the merge patch setting the min and max replicas and the metric targets,
+// the HPA and the EHPA share the same fields.
+func buildAutoscalerPatch(proposed *analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation) ([]byte, error) {
+	spec := map[string]interface{}{}
+	if proposed.MinReplicas != nil {
+		spec["minReplicas"] = *proposed.MinReplicas
+	}
+	if proposed.MaxReplicas != nil {
+		spec["maxReplicas"] = *proposed.MaxReplicas
+	}
+	if len(proposed.Metrics) > 0 {
+		spec["metrics"] = proposed.Metrics
+	}
+	if len(spec) == 0 {
+		return nil, errors.New("the recommendation proposes no replicas or metrics")
+	}
+
+	return json.Marshal(map[string]interface{}{"spec": spec})
+}
+
+// BuildEffectiveHPA builds the EHPA scaling the target of the recommendation with the proposed spec,
+// the EHPA previews the replicas until its strategy is switched to Auto.
+func BuildEffectiveHPA(recommend *analysisv1alpha1.Recommendation, proposed *analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation) (*autoscalingapi.EffectiveHorizontalPodAutoscaler, error) {
+	if proposed.MaxReplicas == nil {
+		return nil, errors.New("the recommendation proposes no max replicas")
+	}
+
+	targetRef := recommend.Spec.TargetRef
+	return &autoscalingapi.EffectiveHorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: autoscalingapi.GroupVersion.String(),
+			Kind:       AutoscalerKindEHPA,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      targetRef.Name,
+			Namespace: targetRef.Namespace,
+		},
+		Spec: autoscalingapi.EffectiveHorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				Kind:       targetRef.Kind,
+				Name:       targetRef.Name,
+				APIVersion: targetRef.APIVersion,
+			},
+			MinReplicas:   proposed.MinReplicas,
+			MaxReplicas:   *proposed.MaxReplicas,
+			ScaleStrategy: autoscalingapi.ScaleStrategyPreview,
+			Metrics:       proposed.Metrics,
+			Prediction:    proposed.Prediction,
+		},
+	}, nil
+}
+
+// adoptToAutoscaler updates the HPA or the EHPA owning the replicas of the target, or creates an EHPA
+// when the target has none and --create-ehpa is set. It returns false when the replicas of the target
+// should be patched instead.
+func (o *RecommendAdoptOptions) adoptToAutoscaler(recommend *analysisv1alpha1.Recommendation) (bool, string, error) {
+	autoscaler, err := FindAutoscaler(o.CommonOptions, recommend.Spec.TargetRef)
+	if err != nil {
+		return true, "", fmt.Errorf("find the autoscaler of the target failed because %v", err)
+	}
+	if autoscaler == nil && !o.CreateEHPA {
+		return false, "", nil
+	}
+
+	proposed, err := ProposedAutoscaling(recommend)
+	if err != nil {
+		return true, "", err
+	}
+
+	var message string
+	if autoscaler == nil {
+		message, err = o.createEffectiveHPA(recommend, proposed)
+	} else {
+		message, err = o.updateAutoscaler(autoscaler, recommend, proposed)
+	}
+
+	return true, message, err
+}
+
+// createEffectiveHPA creates the EHPA with the proposed spec, the min replicas are checked against
+// the replicas of the target by the guardrails. The EHPA is recorded as created by the adoption,
+// so that the rollback deletes it.
+func (o *RecommendAdoptOptions) createEffectiveHPA(recommend *analysisv1alpha1.Recommendation, proposed *analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation) (string, error) {
+	targetRef := recommend.Spec.TargetRef
+	gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the resource of %s %s, %v", targetRef.APIVersion, targetRef.Kind, err)
+	}
+	live, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("adopt the recommend failed because %v", err)
+	}
+
+	proposed, violations, err := o.GuardrailOptions.ApplyAutoscaling(int64(utils.WorkloadReplicas(live)), 0, proposed)
+	if err != nil {
+		return "", err
+	}
+
+	ehpa, err := BuildEffectiveHPA(recommend, proposed)
+	if err != nil {
+		return "", err
+	}
+	ehpa.Spec.ScaleStrategy = autoscalingapi.ScaleStrategy(o.EHPAStrategy)
+	record, err := json.Marshal(AdoptionRecord{
+		Recommendation: recommend.Name,
+		AdoptedTime:    metav1.Now(),
+		Created:        true,
+	})
+	if err != nil {
+		return "", err
+	}
+	ehpa.Annotations = map[string]string{previousValueAnnotation(recommend.Spec.Type): string(record)}
+
+	if o.Diff {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ehpa)
+		if err != nil {
+			return "", err
+		}
+		to, err := toDiffableYaml(&unstructured.Unstructured{Object: content})
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s/%s/%s", strings.ToLower(AutoscalerKindEHPA), ehpa.Namespace, ehpa.Name)
+		if _, err := printUnifiedDiff(nil, to, name+" (none)", name+" ("+recommend.Name+")", colorEnabled(o.CommonOptions.Out, o.NoColor), o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+	}
+
+	createOptions := metav1.CreateOptions{}
+	if o.DryRun {
+		createOptions.DryRun = []string{"All"}
+	}
+	created, err := o.CommonOptions.CraneClient.AutoscalingV1alpha1().EffectiveHorizontalPodAutoscalers(ehpa.Namespace).Create(context.TODO(), ehpa, createOptions)
+	if err != nil {
+		return "", fmt.Errorf("create the %s %s/%s failed because %v", AutoscalerKindEHPA, ehpa.Namespace, ehpa.Name, err)
+	}
+
+	// when dry-run set, print the object unless the diff has been printed
+	if o.DryRun && !o.Diff {
+		printer := printers.NewTypeSetter(cranescheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		if err := printer.PrintObj(created, o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+	}
+
+	message := fmt.Sprintf("created the %s %s/%s with the %s strategy", AutoscalerKindEHPA, ehpa.Namespace, ehpa.Name, ehpa.Spec.ScaleStrategy)
+	if len(violations) > 0 {
+		message += ", clamped by guardrails: " + strings.Join(violations, "; ")
+	}
+
+	return message, nil
+}
+
+// updateAutoscaler patches the min and max replicas and the metrics of the autoscaler like the target
+// of a Replicas recommendation, the overwritten values are recorded on the autoscaler for the rollback.
+func (o *RecommendAdoptOptions) updateAutoscaler(autoscaler *Autoscaler, recommend *analysisv1alpha1.Recommendation, proposed *analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation) (string, error) {
+	gvr := autoscaler.GroupVersionResource()
+	live, err := o.CommonOptions.DynamicClient.Resource(gvr).Namespace(autoscaler.Namespace).Get(context.TODO(), autoscaler.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("get the %s failed because %v", autoscaler, err)
+	}
+
+	// the min replicas of the HPA defaults to 1
+	currentMin, found, _ := unstructured.NestedInt64(live.Object, "spec", "minReplicas")
+	if !found {
+		currentMin = 1
+	}
+	currentMax, _, _ := unstructured.NestedInt64(live.Object, "spec", "maxReplicas")
+	proposed, violations, err := o.GuardrailOptions.ApplyAutoscaling(currentMin, currentMax, proposed)
+	if err != nil {
+		return "", err
+	}
+
+	// autoscaling/v1 has no metrics, only the min and max replicas are adopted
+	if autoscaler.APIVersion == autoscalingv1.SchemeGroupVersion.String() && len(proposed.Metrics) > 0 {
+		klog.Warningf("The %s is served with %s only, skip adopting the proposed metrics.", autoscaler, autoscaler.APIVersion)
+		withoutMetrics := *proposed
+		withoutMetrics.Metrics = nil
+		proposed = &withoutMetrics
+	}
+
+	patch, err := buildAutoscalerPatch(proposed)
+	if err != nil {
+		return "", err
+	}
+	patched, err := mergePatchLocally(live, patch)
+	if err != nil {
+		return "", err
+	}
+	if equality.Semantic.DeepEqual(live.Object["spec"], patched.Object["spec"]) {
+		return "", ErrAlreadyAdopted
+	}
+
+	if o.Diff {
+		from, err := toDiffableYaml(live)
+		if err != nil {
+			return "", err
+		}
+		to, err := toDiffableYaml(patched)
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s/%s/%s", strings.ToLower(autoscaler.Kind), autoscaler.Namespace, autoscaler.Name)
+		if _, err := printUnifiedDiff(from, to, name+" (live)", name+" ("+recommend.Name+")", colorEnabled(o.CommonOptions.Out, o.NoColor), o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+	}
+
+	// keep the record of an earlier adoption and merge the fields it doesn't restore like buildAdoptPatch
+	previousInfo, err := buildAutoscalerPreviousInfo(live, patch)
+	if err != nil {
+		return "", err
+	}
+	annotation := previousValueAnnotation(recommend.Spec.Type)
+	record, err := mergeAdoptionRecord(live.GetAnnotations()[annotation], AdoptionRecord{
+		Recommendation: recommend.Name,
+		AdoptedTime:    metav1.Now(),
+		PreviousInfo:   previousInfo,
+	})
+	if err != nil {
+		return "", fmt.Errorf("the annotation %s on the %s is invalid, %v", annotation, autoscaler, err)
+	}
+	if len(record) > 0 {
+		patch, err = withAnnotation(string(patch), annotation, record)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if o.DryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+	result, err := o.CommonOptions.DynamicClient.Resource(gvr).Namespace(autoscaler.Namespace).Patch(context.TODO(), autoscaler.Name, types.MergePatchType, patch, patchOptions)
+	if err != nil {
+		return "", fmt.Errorf("update the %s failed because %v", autoscaler, err)
+	}
+
+	// when dry-run set, print the object unless the diff has been printed
+	if o.DryRun && !o.Diff {
+		printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		if err := printer.PrintObj(result, o.CommonOptions.Out); err != nil {
+			return "", err
+		}
+	}
+
+	message := fmt.Sprintf("updated the %s", autoscaler)
+	if len(violations) > 0 {
+		message += ", clamped by guardrails: " + strings.Join(violations, "; ")
+	}
+
+	return message, nil
+}
+
+// buildAutoscalerPreviousInfo builds a merge patch restoring the fields of the autoscaler that the patch sets,
+// the fields missing on the live autoscaler are set to null so that the patch removes them.
+func buildAutoscalerPreviousInfo(live *unstructured.Unstructured, patch []byte) (string, error) {
+	patchMap := map[string]interface{}{}
+	if err := json.Unmarshal(patch, &patchMap); err != nil {
+		return "", err
+	}
+	fields, _, _ := unstructured.NestedMap(patchMap, "spec")
+
+	spec := map[string]interface{}{}
+	for field := range fields {
+		value, found, _ := unstructured.NestedFieldCopy(live.Object, "spec", field)
+		if !found {
+			value = nil
+		}
+		spec[field] = value
+	}
+
+	previousInfo, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return "", err
+	}
+
+	return string(previousInfo), nil
+}
+
+// mergePatchLocally applies the json merge patch the autoscalers are patched with
+func mergePatchLocally(live *unstructured.Unstructured, patch []byte) (*unstructured.Unstructured, error) {
+	original, err := live.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	patchedJSON, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(patchedJSON); err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
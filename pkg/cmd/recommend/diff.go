@@ -0,0 +1,306 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	recommendDiffExample = `
+# show the change the specified recommendation would make to its target
+%[1]s recommend diff --name workloads-rule-resource-ntzns -n kube-system
+
+# show the change computed by the api server with a dry-run patch
+%[1]s recommend diff --name workloads-rule-resource-ntzns -n kube-system --server-side
+
+# show the changes of all Resource recommendations in kube-system namespace
+%[1]s recommend diff --namespace kube-system --type Resource
+`
+)
+
+type RecommendDiffOptions struct {
+	CommonOptions *options.CommonOptions
+	FilterOptions *RecommendFilterOptions
+
+	Name       string
+	ServerSide bool
+	NoColor    bool
+}
+
+func NewRecommendDiffOptions() *RecommendDiffOptions {
+	return &RecommendDiffOptions{
+		CommonOptions: options.NewCommonOptions(),
+		FilterOptions: NewRecommendFilterOptions(),
+	}
+}
+
+func NewCmdRecommendDiff() *cobra.Command {
+	o := NewRecommendDiffOptions()
+
+	command := &cobra.Command{
+		Use:     "diff",
+		Short:   "Show the change a recommend would make to resource",
+		Example: fmt.Sprintf(recommendDiffExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendDiffExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.AddFlags(command)
+	o.CommonOptions.AddCommonFlag(command)
+
+	return command
+}
+
+func (o *RecommendDiffOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 && o.FilterOptions.IsEmpty() {
+		return errors.New("please specify the recommend name or the selectors of recommendations")
+	}
+
+	if len(o.Name) > 0 && (!o.FilterOptions.IsEmpty() || o.FilterOptions.AllNamespaces) {
+		return errors.New("the recommend name can't be used together with the selectors of recommendations")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 && !o.FilterOptions.AllNamespaces {
+		return errors.New("please specify the recommend namespace")
+	}
+
+	return nil
+}
+
+func (o *RecommendDiffOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *RecommendDiffOptions) Run() error {
+	var recommendations []analysisv1alpha1.Recommendation
+	if len(o.Name) > 0 {
+		recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+		}
+		recommendations = append(recommendations, *recommend)
+	} else {
+		var err error
+		recommendations, err = o.FilterOptions.ListRecommendations(o.CommonOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	// diff the targets one by one and continue past the failed ones
+	color := colorEnabled(o.CommonOptions.Out, o.NoColor)
+	failed := 0
+	for i := range recommendations {
+		if !isAdoptable(&recommendations[i]) {
+			continue
+		}
+		if len(recommendations[i].Status.RecommendedInfo) == 0 {
+			klog.Infof("skip the recommendation %s/%s, the recommendation has no recommended value yet", recommendations[i].Namespace, recommendations[i].Name)
+			continue
+		}
+		if err := DiffRecommendation(o.CommonOptions, &recommendations[i], o.ServerSide, color, o.CommonOptions.Out); err != nil {
+			klog.Errorf("Failed to diff recommendation %s/%s, %v.", recommendations[i].Namespace, recommendations[i].Name, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to diff %d of %d recommendations", failed, len(recommendations))
+	}
+
+	return nil
+}
+
+// colorEnabled colors the diff only when it is printed to a terminal, --no-color turns it off anyway
+func colorEnabled(out io.Writer, noColor bool) bool {
+	if noColor {
+		return false
+	}
+
+	file, ok := out.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// DiffRecommendation prints the unified diff between the live target of the recommendation
+// and the target patched with the RecommendedInfo.
+func DiffRecommendation(commonOptions *options.CommonOptions, recommend *analysisv1alpha1.Recommendation, serverSide bool, color bool, out io.Writer) error {
+	targetRef := recommend.Spec.TargetRef
+	gvr, err := utils.GetGroupVersionResource(commonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		return fmt.Errorf("failed to get the resource of %s %s, %v", targetRef.APIVersion, targetRef.Kind, err)
+	}
+
+	live, err := commonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the target %s/%s, %v", targetRef.Namespace, targetRef.Name, err)
+	}
+
+	var patched *unstructured.Unstructured
+	if serverSide {
+		patched, err = commonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Patch(context.TODO(), targetRef.Name, types.StrategicMergePatchType, []byte(recommend.Status.RecommendedInfo), metav1.PatchOptions{DryRun: []string{"All"}})
+	} else {
+		patched, err = patchLocally(live, []byte(recommend.Status.RecommendedInfo))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to patch the target %s/%s, %v", targetRef.Namespace, targetRef.Name, err)
+	}
+
+	from, err := toDiffableYaml(live)
+	if err != nil {
+		return err
+	}
+	to, err := toDiffableYaml(patched)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s/%s/%s", strings.ToLower(targetRef.Kind), targetRef.Namespace, targetRef.Name)
+	changed, err := printUnifiedDiff(from, to, name+" (live)", name+" ("+recommend.Name+")", color, out)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		fmt.Fprintf(out, "%s is already up to date with %s\n", name, recommend.Name)
+	}
+
+	return nil
+}
+
+// printUnifiedDiff prints the unified diff of the contents and returns whether they differ
+func printUnifiedDiff(from, to []byte, fromFile, toFile string, color bool, out io.Writer) (bool, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(diff) == 0 {
+		return false, nil
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		fmt.Fprintln(out, colorDiffLine(line, color))
+	}
+
+	return true, nil
+}
+
+// patchLocally applies the patch as a strategic merge patch for the built-in kinds,
+// and as a json merge patch for the kinds unknown to the scheme such as CRDs.
+func patchLocally(live *unstructured.Unstructured, patch []byte) (*unstructured.Unstructured, error) {
+	original, err := live.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var patchedJSON []byte
+	if dataStruct, err := scheme.Scheme.New(live.GroupVersionKind()); err == nil {
+		patchedJSON, err = strategicpatch.StrategicMergePatch(original, patch, dataStruct)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		patchedJSON, err = jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(patchedJSON); err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+// toDiffableYaml drops the fields changed by the api server on every write,
+// so that the diff only contains the changes of the recommendation.
+func toDiffableYaml(obj *unstructured.Unstructured) ([]byte, error) {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	return yaml.Marshal(obj.Object)
+}
+
+func colorDiffLine(line string, color bool) string {
+	if !color {
+		return line
+	}
+
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return text.Bold.Sprint(line)
+	case strings.HasPrefix(line, "+"):
+		return text.FgGreen.Sprint(line)
+	case strings.HasPrefix(line, "-"):
+		return text.FgRed.Sprint(line)
+	case strings.HasPrefix(line, "@@"):
+		return text.FgCyan.Sprint(line)
+	default:
+		return line
+	}
+}
+
+func (o *RecommendDiffOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for recommend")
+	cmd.Flags().BoolVarP(&o.ServerSide, "server-side", "", false, "Compute the patched object with a server side dry-run instead of locally")
+	cmd.Flags().BoolVarP(&o.NoColor, "no-color", "", false, "Print the diff without color, the diff is only colored on a terminal")
+	o.FilterOptions.AddFlags(cmd)
+}
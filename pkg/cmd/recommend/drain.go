@@ -0,0 +1,188 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+const (
+	DefaultDrainTimeout = 5 * time.Minute
+
+	// evictionRetryInterval is how long to wait before evicting again a pod protected by a PodDisruptionBudget
+	evictionRetryInterval = 5 * time.Second
+	// drainConcurrency is the number of pods evicted at the same time
+	drainConcurrency = 10
+
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// ListNodePods lists the pods scheduled to the node
+func ListNodePods(commonOptions *options.CommonOptions, nodeName string) ([]corev1.Pod, error) {
+	podList, err := commonOptions.KubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return podList.Items, nil
+}
+
+// PodsToEvict returns the pods evicted when draining the node. The DaemonSet pods and the mirror
+// pods stay on the node, and the finished pods don't need to be evicted.
+func PodsToEvict(pods []corev1.Pod) []corev1.Pod {
+	var evicted []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, exist := pod.Annotations[mirrorPodAnnotation]; exist {
+			continue
+		}
+		if controller := metav1.GetControllerOf(&pod); controller != nil && controller.Kind == "DaemonSet" {
+			continue
+		}
+		evicted = append(evicted, pod)
+	}
+
+	return evicted
+}
+
+// CordonNode marks the node unschedulable and returns the patched node
+func CordonNode(commonOptions *options.CommonOptions, nodeName string, dryRun bool) (*corev1.Node, error) {
+	patchOptions := metav1.PatchOptions{}
+	if dryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+
+	return commonOptions.KubeClient.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.StrategicMergePatchType, []byte(`{"spec":{"unschedulable":true}}`), patchOptions)
+}
+
+// CheckUnmanagedPods refuses to evict the pods not managed by a controller unless force is set,
+// they are not recreated anywhere.
+func CheckUnmanagedPods(pods []corev1.Pod, force bool) error {
+	if force {
+		return nil
+	}
+
+	var unmanaged []string
+	for _, pod := range pods {
+		if metav1.GetControllerOf(&pod) == nil {
+			unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	if len(unmanaged) > 0 {
+		return fmt.Errorf("the pods %s are not managed by a controller, use --force to evict them", strings.Join(unmanaged, ","))
+	}
+
+	return nil
+}
+
+// DrainNode evicts the pods from the node with the eviction api, so that the PodDisruptionBudgets are
+// respected: the evictions refused by a budget are retried until the timeout.
+func DrainNode(commonOptions *options.CommonOptions, nodeName string, force bool, timeout time.Duration, dryRun bool) ([]corev1.Pod, error) {
+	pods, err := ListNodePods(commonOptions, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	pods = PodsToEvict(pods)
+
+	if err := CheckUnmanagedPods(pods, force); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	var lock sync.Mutex
+	var failed []string
+	// every pod is tried so that the ones skipped on timeout are reported as failed
+	workqueue.ParallelizeUntil(context.TODO(), drainConcurrency, len(pods), func(i int) {
+		if err := evictPod(ctx, commonOptions, &pods[i], dryRun); err != nil {
+			klog.Errorf("Failed to evict the pod %s/%s, %v.", pods[i].Namespace, pods[i].Name, err)
+			lock.Lock()
+			defer lock.Unlock()
+			failed = append(failed, pods[i].Namespace+"/"+pods[i].Name)
+			return
+		}
+		klog.V(2).Infof("evicted the pod %s/%s", pods[i].Namespace, pods[i].Name)
+	})
+	if len(failed) > 0 {
+		return pods, fmt.Errorf("failed to evict the pods %s from node %s", strings.Join(failed, ","), nodeName)
+	}
+
+	return pods, nil
+}
+
+// evictPod evicts the pod and waits until it is deleted
+func evictPod(ctx context.Context, commonOptions *options.CommonOptions, pod *corev1.Pod, dryRun bool) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+	if dryRun {
+		eviction.DeleteOptions = &metav1.DeleteOptions{DryRun: []string{"All"}}
+	}
+
+	client := commonOptions.KubeClient.CoreV1().Pods(pod.Namespace)
+	err := wait.PollImmediateUntil(evictionRetryInterval, func() (bool, error) {
+		err := client.EvictV1(ctx, eviction)
+		switch {
+		case err == nil:
+			return true, nil
+		case apierrors.IsNotFound(err):
+			return true, nil
+		case apierrors.IsTooManyRequests(err):
+			// the eviction violates a PodDisruptionBudget for now
+			klog.V(2).Infof("the eviction of pod %s/%s is refused, retrying, %v", pod.Namespace, pod.Name, err)
+			return false, nil
+		default:
+			return false, err
+		}
+	}, ctx.Done())
+	if err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) {
+			return fmt.Errorf("timed out evicting the pod, it may be protected by a PodDisruptionBudget")
+		}
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	err = wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		current, err := client.Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// a pod with the same name is recreated by a StatefulSet
+		return current.UID != pod.UID, nil
+	}, ctx.Done())
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("timed out waiting for the pod to be deleted")
+	}
+
+	return err
+}
@@ -0,0 +1,451 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	recommendExportExample = `
+# export the recommendations in kube-system namespace as kustomize strategic merge patches
+%[1]s recommend export --namespace kube-system --output-dir ./patches
+
+# export the recommendations of a recommendation rule as JSON6902 patches
+%[1]s recommend export --ruleName workloads-rule -A --format patch --output-dir ./patches
+
+# export the specified recommendation as a helm values overlay
+%[1]s recommend export --name workloads-rule-resource-ntzns --namespace default --format helm-values
+`
+)
+
+const (
+	ExportFormatKustomize  = "kustomize"
+	ExportFormatPatch      = "patch"
+	ExportFormatHelmValues = "helm-values"
+
+	DefaultExportDir = "crane-export"
+)
+
+// exportTarget is the recommended requests and replicas of a target
+type exportTarget struct {
+	TargetRef corev1.ObjectReference
+
+	// nil if there is no Resource or Replicas recommendation of the target
+	Requests []utils.ContainerRequest
+	Replicas *int32
+}
+
+type RecommendExportOptions struct {
+	CommonOptions *options.CommonOptions
+	FilterOptions *RecommendFilterOptions
+
+	Name      string
+	Format    string
+	OutputDir string
+}
+
+func NewRecommendExportOptions() *RecommendExportOptions {
+	return &RecommendExportOptions{
+		CommonOptions: options.NewCommonOptions(),
+		FilterOptions: NewRecommendFilterOptions(),
+	}
+}
+
+func NewCmdRecommendExport() *cobra.Command {
+	o := NewRecommendExportOptions()
+
+	command := &cobra.Command{
+		Use:     "export",
+		Short:   "Export the recommendations as patch files to review and merge into the manifests",
+		Example: fmt.Sprintf(recommendExportExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendExportExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.AddFlags(command)
+	o.CommonOptions.AddCommonFlag(command)
+
+	return command
+}
+
+func (o *RecommendExportOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) > 0 && (!o.FilterOptions.IsEmpty() || o.FilterOptions.AllNamespaces) {
+		return errors.New("the recommend name can't be used together with the selectors of recommendations")
+	}
+
+	switch o.Format {
+	case ExportFormatKustomize, ExportFormatPatch, ExportFormatHelmValues:
+	default:
+		return fmt.Errorf("unsupported format %s, please specify one of %s, %s, %s", o.Format, ExportFormatKustomize, ExportFormatPatch, ExportFormatHelmValues)
+	}
+
+	if len(o.OutputDir) == 0 {
+		return errors.New("please specify the output directory with --output-dir")
+	}
+
+	return nil
+}
+
+func (o *RecommendExportOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *RecommendExportOptions) Run() error {
+	var recommendations []analysisv1alpha1.Recommendation
+	if len(o.Name) > 0 {
+		recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+		}
+		recommendations = append(recommendations, *recommend)
+	} else {
+		var err error
+		recommendations, err = o.FilterOptions.ListRecommendations(o.CommonOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	targets := collectExportTargets(recommendations)
+	if len(targets) == 0 {
+		klog.Infof("no recommendation to export")
+		return nil
+	}
+
+	if err := os.MkdirAll(o.OutputDir, 0755); err != nil {
+		return err
+	}
+
+	var files []string
+	failed := 0
+	for _, target := range targets {
+		file, content, err := o.export(target)
+		if err != nil {
+			klog.Warningf("Failed to export the recommendations of %s %s/%s, %v.", target.TargetRef.Kind, target.TargetRef.Namespace, target.TargetRef.Name, err)
+			failed++
+			continue
+		}
+
+		if err := os.WriteFile(filepath.Join(o.OutputDir, file), content, 0644); err != nil {
+			return err
+		}
+		klog.Infof("exported %s", filepath.Join(o.OutputDir, file))
+		files = append(files, file)
+	}
+
+	// the kustomization lists the patches so that the directory can be referenced from an overlay
+	if o.Format == ExportFormatKustomize && len(files) > 0 {
+		content, err := buildKustomization(files)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(o.OutputDir, "kustomization.yaml"), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to export %d of %d targets", failed, len(targets))
+	}
+
+	return nil
+}
+
+// export returns the name and the content of the file of the target in the format
+func (o *RecommendExportOptions) export(target *exportTarget) (string, []byte, error) {
+	name := exportFileName(target.TargetRef)
+	header := fmt.Sprintf("# recommended by crane for %s %s/%s\n", target.TargetRef.Kind, target.TargetRef.Namespace, target.TargetRef.Name)
+
+	switch o.Format {
+	case ExportFormatPatch:
+		// the container indexes in the json patch are resolved from the live object
+		live, err := getTarget(o.CommonOptions, target.TargetRef)
+		if err != nil {
+			return "", nil, err
+		}
+		content, err := buildJSONPatch(target, live)
+		if err != nil {
+			return "", nil, err
+		}
+		return name + ".json", content, nil
+	case ExportFormatHelmValues:
+		content, err := yaml.Marshal(buildHelmValues(target))
+		if err != nil {
+			return "", nil, err
+		}
+		return name + ".values.yaml", append([]byte(header), content...), nil
+	default:
+		content, err := yaml.Marshal(buildStrategicMergePatch(target))
+		if err != nil {
+			return "", nil, err
+		}
+		return name + ".yaml", append([]byte(header), content...), nil
+	}
+}
+
+// collectExportTargets merges the Resource and Replicas recommendations of each target, ordered by the target
+func collectExportTargets(recommendations []analysisv1alpha1.Recommendation) []*exportTarget {
+	var keys []string
+	targets := map[string]*exportTarget{}
+	for i := range recommendations {
+		recommend := &recommendations[i]
+		if !isAdoptable(recommend) {
+			continue
+		}
+		if len(recommend.Status.RecommendedInfo) == 0 {
+			klog.Warningf("The recommendation %s/%s has no recommended value yet, skipped.", recommend.Namespace, recommend.Name)
+			continue
+		}
+
+		targetRef := recommend.Spec.TargetRef
+		if len(targetRef.Namespace) == 0 {
+			targetRef.Namespace = recommend.Namespace
+		}
+		key := strings.Join([]string{targetRef.Namespace, targetRef.APIVersion, targetRef.Kind, targetRef.Name}, "/")
+		target, exist := targets[key]
+		if !exist {
+			target = &exportTarget{TargetRef: targetRef}
+			targets[key] = target
+			keys = append(keys, key)
+		}
+
+		if recommend.Spec.Type == analysisv1alpha1.AnalysisTypeResource {
+			requests, err := utils.DecodeResourceInfo(recommend.Status.RecommendedInfo)
+			if err != nil {
+				klog.Warningf("Failed to decode the recommendation %s/%s, %v.", recommend.Namespace, recommend.Name, err)
+				continue
+			}
+			target.Requests = requests
+		} else {
+			replicas, err := utils.DecodeReplicasInfo(recommend.Status.RecommendedInfo)
+			if err != nil {
+				klog.Warningf("Failed to decode the recommendation %s/%s, %v.", recommend.Namespace, recommend.Name, err)
+				continue
+			}
+			target.Replicas = &replicas
+		}
+	}
+	sort.Strings(keys)
+
+	var result []*exportTarget
+	for _, key := range keys {
+		if targets[key].Requests != nil || targets[key].Replicas != nil {
+			result = append(result, targets[key])
+		}
+	}
+
+	return result
+}
+
+func exportFileName(targetRef corev1.ObjectReference) string {
+	return strings.ToLower(fmt.Sprintf("%s_%s_%s", targetRef.Namespace, targetRef.Kind, targetRef.Name))
+}
+
+// requestsMap returns the non-zero recommended requests of the container
+func requestsMap(request utils.ContainerRequest) map[string]interface{} {
+	requests := map[string]interface{}{}
+	if !request.Cpu.IsZero() {
+		requests[string(corev1.ResourceCPU)] = request.Cpu.String()
+	}
+	if !request.Memory.IsZero() {
+		requests[string(corev1.ResourceMemory)] = request.Memory.String()
+	}
+
+	return requests
+}
+
+// buildStrategicMergePatch returns the strategic merge patch of the target, the containers are merged by name
+func buildStrategicMergePatch(target *exportTarget) map[string]interface{} {
+	spec := map[string]interface{}{}
+	if target.Replicas != nil {
+		spec["replicas"] = *target.Replicas
+	}
+
+	var containers []interface{}
+	for _, request := range target.Requests {
+		requests := requestsMap(request)
+		if len(requests) == 0 {
+			continue
+		}
+		containers = append(containers, map[string]interface{}{
+			"name":      request.Name,
+			"resources": map[string]interface{}{"requests": requests},
+		})
+	}
+	if len(containers) > 0 {
+		spec["template"] = map[string]interface{}{
+			"spec": map[string]interface{}{"containers": containers},
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": target.TargetRef.APIVersion,
+		"kind":       target.TargetRef.Kind,
+		"metadata": map[string]interface{}{
+			"name":      target.TargetRef.Name,
+			"namespace": target.TargetRef.Namespace,
+		},
+		"spec": spec,
+	}
+}
+
+// buildJSONPatch returns the JSON6902 patch of the target, the paths which don't exist
+// in the live object are added with their parents.
+func buildJSONPatch(target *exportTarget, live *unstructured.Unstructured) ([]byte, error) {
+	type operation struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+
+	// an empty patch is [] rather than null
+	operations := []operation{}
+	if target.Replicas != nil {
+		operations = append(operations, operation{Op: "add", Path: "/spec/replicas", Value: *target.Replicas})
+	}
+
+	containers, _, err := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range target.Requests {
+		requests := requestsMap(request)
+		if len(requests) == 0 {
+			continue
+		}
+
+		index := -1
+		var container map[string]interface{}
+		for i := range containers {
+			if c, ok := containers[i].(map[string]interface{}); ok && c["name"] == request.Name {
+				index, container = i, c
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("container %s not found", request.Name)
+		}
+
+		path := fmt.Sprintf("/spec/template/spec/containers/%d/resources", index)
+		_, hasResources, _ := unstructured.NestedFieldNoCopy(container, "resources")
+		_, hasRequests, _ := unstructured.NestedFieldNoCopy(container, "resources", "requests")
+		switch {
+		case !hasResources:
+			operations = append(operations, operation{Op: "add", Path: path, Value: map[string]interface{}{"requests": requests}})
+		case !hasRequests:
+			operations = append(operations, operation{Op: "add", Path: path + "/requests", Value: requests})
+		default:
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				if value, exist := requests[string(name)]; exist {
+					operations = append(operations, operation{Op: "add", Path: path + "/requests/" + string(name), Value: value})
+				}
+			}
+		}
+	}
+
+	content, err := json.MarshalIndent(operations, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
+
+// buildHelmValues returns the values overlay following the layout of the charts created by helm create,
+// the containers other than the only one are keyed by their names under containers.
+func buildHelmValues(target *exportTarget) map[string]interface{} {
+	values := map[string]interface{}{}
+	if target.Replicas != nil {
+		values["replicaCount"] = *target.Replicas
+	}
+
+	if len(target.Requests) == 1 {
+		if requests := requestsMap(target.Requests[0]); len(requests) > 0 {
+			values["resources"] = map[string]interface{}{"requests": requests}
+		}
+	} else if len(target.Requests) > 1 {
+		containers := map[string]interface{}{}
+		for _, request := range target.Requests {
+			if requests := requestsMap(request); len(requests) > 0 {
+				containers[request.Name] = map[string]interface{}{
+					"resources": map[string]interface{}{"requests": requests},
+				}
+			}
+		}
+		values["containers"] = containers
+	}
+
+	return values
+}
+
+func buildKustomization(files []string) ([]byte, error) {
+	var patches []interface{}
+	for _, file := range files {
+		patches = append(patches, map[string]interface{}{"path": file})
+	}
+
+	return yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"patches":    patches,
+	})
+}
+
+func getTarget(commonOptions *options.CommonOptions, targetRef corev1.ObjectReference) (*unstructured.Unstructured, error) {
+	gvr, err := utils.GetGroupVersionResource(commonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return commonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+}
+
+func (o *RecommendExportOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for recommend")
+	cmd.Flags().StringVarP(&o.Format, "format", "", ExportFormatKustomize, "The format of the exported files, one of kustomize, patch or helm-values")
+	cmd.Flags().StringVarP(&o.OutputDir, "output-dir", "", DefaultExportDir, "The directory to write the exported files into, one file per target")
+	o.FilterOptions.AddFlags(cmd)
+}
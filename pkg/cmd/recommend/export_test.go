@@ -0,0 +1,169 @@
+package recommend
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+func TestBuildJSONPatch(t *testing.T) {
+	containers := []interface{}{
+		map[string]interface{}{
+			"name": "app",
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{"cpu": "1", "memory": "1Gi"},
+			},
+		},
+		map[string]interface{}{
+			"name":      "sidecar",
+			"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}},
+		},
+		map[string]interface{}{
+			"name": "proxy",
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		target   exportTarget
+		expected string
+		// the expected requests of the containers after applying the patch
+		expectedRequests map[string]map[string]string
+		expectFail       bool
+	}{
+		{
+			name:     "nothing to export",
+			expected: `[]`,
+		},
+		{
+			name:     "replicas",
+			target:   exportTarget{Replicas: int32Ptr(3)},
+			expected: `[{"op":"add","path":"/spec/replicas","value":3}]`,
+		},
+		{
+			name: "existing requests replaced one by one",
+			target: exportTarget{Requests: []utils.ContainerRequest{
+				{Name: "app", Cpu: resource.MustParse("250m"), Memory: resource.MustParse("512Mi")},
+			}},
+			expected: `[{"op":"add","path":"/spec/template/spec/containers/0/resources/requests/cpu","value":"250m"},` +
+				`{"op":"add","path":"/spec/template/spec/containers/0/resources/requests/memory","value":"512Mi"}]`,
+			expectedRequests: map[string]map[string]string{"app": {"cpu": "250m", "memory": "512Mi"}},
+		},
+		{
+			name: "missing recommended memory kept",
+			target: exportTarget{Requests: []utils.ContainerRequest{
+				{Name: "app", Cpu: resource.MustParse("250m")},
+			}},
+			expected:         `[{"op":"add","path":"/spec/template/spec/containers/0/resources/requests/cpu","value":"250m"}]`,
+			expectedRequests: map[string]map[string]string{"app": {"cpu": "250m", "memory": "1Gi"}},
+		},
+		{
+			name: "requests added to the resources",
+			target: exportTarget{Requests: []utils.ContainerRequest{
+				{Name: "sidecar", Cpu: resource.MustParse("100m")},
+			}},
+			expected:         `[{"op":"add","path":"/spec/template/spec/containers/1/resources/requests","value":{"cpu":"100m"}}]`,
+			expectedRequests: map[string]map[string]string{"sidecar": {"cpu": "100m"}},
+		},
+		{
+			name: "resources added to the container",
+			target: exportTarget{Requests: []utils.ContainerRequest{
+				{Name: "proxy", Memory: resource.MustParse("64Mi")},
+			}},
+			expected:         `[{"op":"add","path":"/spec/template/spec/containers/2/resources","value":{"requests":{"memory":"64Mi"}}}]`,
+			expectedRequests: map[string]map[string]string{"proxy": {"memory": "64Mi"}},
+		},
+		{
+			name: "zero recommended requests skipped",
+			target: exportTarget{Requests: []utils.ContainerRequest{
+				{Name: "app"},
+				{Name: "missing"},
+			}},
+			expected: `[]`,
+		},
+		{
+			name: "unmatched container",
+			target: exportTarget{Requests: []utils.ContainerRequest{
+				{Name: "missing", Cpu: resource.MustParse("100m")},
+			}},
+			expectFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			live := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{"containers": containers},
+					},
+				},
+			}}
+
+			content, err := buildJSONPatch(&tc.target, live)
+			if tc.expectFail {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, content); err != nil {
+				t.Fatalf("invalid patch %s, %v", content, err)
+			}
+			if compacted.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, compacted.String())
+			}
+
+			// the patch applies to the live object
+			patch, err := jsonpatch.DecodePatch(content)
+			if err != nil {
+				t.Fatalf("invalid patch %s, %v", content, err)
+			}
+			original, err := live.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			patchedJSON, err := patch.Apply(original)
+			if err != nil {
+				t.Fatalf("failed to apply the patch %s, %v", content, err)
+			}
+			patched := &unstructured.Unstructured{}
+			if err := patched.UnmarshalJSON(patchedJSON); err != nil {
+				t.Fatal(err)
+			}
+
+			patchedContainers, _, _ := unstructured.NestedSlice(patched.Object, "spec", "template", "spec", "containers")
+			for name, expected := range tc.expectedRequests {
+				for _, container := range patchedContainers {
+					containerMap := container.(map[string]interface{})
+					if containerMap["name"] != name {
+						continue
+					}
+					requests, _, _ := unstructured.NestedStringMap(containerMap, "resources", "requests")
+					if len(requests) != len(expected) {
+						t.Errorf("expected the requests of container %s to be %v, got %v", name, expected, requests)
+					}
+					for resourceName, value := range expected {
+						if requests[resourceName] != value {
+							t.Errorf("expected the %s request of container %s to be %s, got %s", resourceName, name, value, requests[resourceName])
+						}
+					}
+				}
+			}
+		})
+	}
+}
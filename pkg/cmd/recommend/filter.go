@@ -0,0 +1,146 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+// RecommendFilterOptions holds the flags used to select recommendations,
+// it is shared by the commands that work on a set of recommendations.
+type RecommendFilterOptions struct {
+	Type          string
+	TargetKind    string
+	TargetName    string
+	RuleName      string
+	LabelSelector string
+	AllNamespaces bool
+}
+
+func NewRecommendFilterOptions() *RecommendFilterOptions {
+	return &RecommendFilterOptions{}
+}
+
+// Validate ensures that the filters are valid
+func (o *RecommendFilterOptions) Validate() error {
+	if len(o.Type) > 0 {
+		typeExist := false
+		for _, recommenderType := range analysisv1alpha1.AllRecommenderType {
+			if recommenderType == o.Type {
+				typeExist = true
+			}
+		}
+		if !typeExist {
+			return fmt.Errorf("the recommender type not supported %s", o.Type)
+		}
+	}
+
+	if len(o.LabelSelector) > 0 {
+		if _, err := labels.Parse(o.LabelSelector); err != nil {
+			return fmt.Errorf("invalid label selector %s, %v", o.LabelSelector, err)
+		}
+	}
+
+	return nil
+}
+
+// IsEmpty returns true when no filter is specified, --all-namespaces is not a filter,
+// so that the commands working on all the recommendations of the cluster need a real one.
+func (o *RecommendFilterOptions) IsEmpty() bool {
+	return len(o.Type) == 0 && len(o.TargetKind) == 0 && len(o.TargetName) == 0 &&
+		len(o.RuleName) == 0 && len(o.LabelSelector) == 0
+}
+
+// Namespace returns the namespace to list recommendations from, empty means all namespaces
+func (o *RecommendFilterOptions) Namespace(commonOptions *options.CommonOptions) string {
+	if o.AllNamespaces {
+		return ""
+	}
+
+	return *commonOptions.ConfigFlags.Namespace
+}
+
+// ToListOptions converts the filters to the label selector of a list request
+func (o *RecommendFilterOptions) ToListOptions() metav1.ListOptions {
+	query := utils.NewQuery()
+	if len(o.Type) > 0 {
+		query.LabelSelector[RecommendationRuleRecommenderLabel] = o.Type
+	}
+
+	if len(o.TargetKind) > 0 {
+		query.LabelSelector[RecommendationRuleTargetKindLabel] = o.TargetKind
+	}
+
+	if len(o.TargetName) > 0 {
+		query.LabelSelector[RecommendationRuleTargetNameLabel] = o.TargetName
+	}
+
+	if len(o.RuleName) > 0 {
+		query.LabelSelector[RecommendationRuleNameLabel] = o.RuleName
+	}
+
+	selector := ""
+	for label, value := range query.LabelSelector {
+		selector += label + "=" + value + ","
+	}
+	if len(o.LabelSelector) > 0 {
+		selector += o.LabelSelector + ","
+	}
+	// remove the last ","
+	if len(selector) > 0 {
+		selector = selector[:len(selector)-1]
+	}
+
+	return metav1.ListOptions{
+		LabelSelector: selector,
+	}
+}
+
+// ListRecommendations lists the recommendations matching the filters
+func (o *RecommendFilterOptions) ListRecommendations(commonOptions *options.CommonOptions) ([]analysisv1alpha1.Recommendation, error) {
+	recommendResult, err := commonOptions.CraneClient.AnalysisV1alpha1().Recommendations(o.Namespace(commonOptions)).List(context.TODO(), o.ToListOptions())
+	if err != nil {
+		klog.Errorf("Failed to get recommend result, %v.", err)
+		return nil, err
+	}
+
+	return recommendResult.Items, nil
+}
+
+// ListWatch returns the ListerWatcher of the recommendations matching the filters
+func (o *RecommendFilterOptions) ListWatch(commonOptions *options.CommonOptions) cache.ListerWatcher {
+	client := commonOptions.CraneClient.AnalysisV1alpha1().Recommendations(o.Namespace(commonOptions))
+	labelSelector := o.ToListOptions().LabelSelector
+
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			return client.List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			return client.Watch(context.TODO(), options)
+		},
+	}
+}
+
+func (o *RecommendFilterOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Type, "type", "", "", "Select recommendation with specify recommend type[Resource, Replicas, IdleNode]")
+	cmd.Flags().StringVarP(&o.TargetKind, "targetKind", "", "", "Select recommendation with specify recommendation target kind")
+	cmd.Flags().StringVarP(&o.TargetName, "targetName", "", "", "Select recommendation with specify recommendation target name")
+	cmd.Flags().StringVarP(&o.RuleName, "ruleName", "", "", "Select recommendation with specify recommendationRule name")
+	cmd.Flags().StringVarP(&o.LabelSelector, "selector", "l", "", "Selector (label query) to select recommendations, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2)")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
+}
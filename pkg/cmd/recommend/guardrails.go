@@ -0,0 +1,391 @@
+package recommend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+const (
+	GuardrailActionRefuse = "refuse"
+	GuardrailActionClamp  = "clamp"
+)
+
+// Guardrails limit how far an adopted recommendation may move the current values of the target.
+// The percentages are relative to the current values, a nil field means no limit.
+type Guardrails struct {
+	Action string `json:"action,omitempty"`
+
+	MaxCpuDecreasePercent      *float64 `json:"maxCpuDecreasePercent,omitempty"`
+	MaxCpuIncreasePercent      *float64 `json:"maxCpuIncreasePercent,omitempty"`
+	MaxMemoryDecreasePercent   *float64 `json:"maxMemoryDecreasePercent,omitempty"`
+	MaxMemoryIncreasePercent   *float64 `json:"maxMemoryIncreasePercent,omitempty"`
+	MaxReplicasDecreasePercent *float64 `json:"maxReplicasDecreasePercent,omitempty"`
+	MaxReplicasIncreasePercent *float64 `json:"maxReplicasIncreasePercent,omitempty"`
+
+	// MinCpu and MinMemory are the minimum requests of every container
+	MinCpu    string `json:"minCpu,omitempty"`
+	MinMemory string `json:"minMemory,omitempty"`
+
+	// MinMemoryLimitRatio keeps the reduced memory request above this ratio of the current memory limit
+	MinMemoryLimitRatio *float64 `json:"minMemoryLimitRatio,omitempty"`
+}
+
+// GuardrailError is returned when a recommendation is refused by the guardrails
+type GuardrailError struct {
+	Violations []string
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("refused by guardrails: %s", strings.Join(e.Violations, "; "))
+}
+
+type GuardrailOptions struct {
+	ConfigFile string
+
+	Guardrails Guardrails
+
+	minCpu    resource.Quantity
+	minMemory resource.Quantity
+
+	flagAction                     string
+	flagMaxCpuDecreasePercent      float64
+	flagMaxCpuIncreasePercent      float64
+	flagMaxMemoryDecreasePercent   float64
+	flagMaxMemoryIncreasePercent   float64
+	flagMaxReplicasDecreasePercent float64
+	flagMaxReplicasIncreasePercent float64
+	flagMinCpu                     string
+	flagMinMemory                  string
+	flagMinMemoryLimitRatio        float64
+}
+
+func NewGuardrailOptions() *GuardrailOptions {
+	return &GuardrailOptions{}
+}
+
+// Complete loads the guardrails from the config file and overrides them with the flags
+func (o *GuardrailOptions) Complete(cmd *cobra.Command) error {
+	if len(o.ConfigFile) > 0 {
+		content, err := os.ReadFile(o.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to read guardrails config %s, %v", o.ConfigFile, err)
+		}
+		if err := yaml.UnmarshalStrict(content, &o.Guardrails); err != nil {
+			return fmt.Errorf("failed to parse guardrails config %s, %v", o.ConfigFile, err)
+		}
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("guardrail-action") {
+		o.Guardrails.Action = o.flagAction
+	}
+	for _, percentFlag := range []struct {
+		name   string
+		value  float64
+		target **float64
+	}{
+		{"max-cpu-decrease", o.flagMaxCpuDecreasePercent, &o.Guardrails.MaxCpuDecreasePercent},
+		{"max-cpu-increase", o.flagMaxCpuIncreasePercent, &o.Guardrails.MaxCpuIncreasePercent},
+		{"max-memory-decrease", o.flagMaxMemoryDecreasePercent, &o.Guardrails.MaxMemoryDecreasePercent},
+		{"max-memory-increase", o.flagMaxMemoryIncreasePercent, &o.Guardrails.MaxMemoryIncreasePercent},
+		{"max-replicas-decrease", o.flagMaxReplicasDecreasePercent, &o.Guardrails.MaxReplicasDecreasePercent},
+		{"max-replicas-increase", o.flagMaxReplicasIncreasePercent, &o.Guardrails.MaxReplicasIncreasePercent},
+		{"min-memory-limit-ratio", o.flagMinMemoryLimitRatio, &o.Guardrails.MinMemoryLimitRatio},
+	} {
+		if flags.Changed(percentFlag.name) {
+			value := percentFlag.value
+			*percentFlag.target = &value
+		}
+	}
+	if flags.Changed("min-cpu") {
+		o.Guardrails.MinCpu = o.flagMinCpu
+	}
+	if flags.Changed("min-memory") {
+		o.Guardrails.MinMemory = o.flagMinMemory
+	}
+
+	if len(o.Guardrails.Action) == 0 {
+		o.Guardrails.Action = GuardrailActionRefuse
+	}
+
+	return nil
+}
+
+// Validate ensures that the guardrails are valid
+func (o *GuardrailOptions) Validate() error {
+	if o.Guardrails.Action != GuardrailActionRefuse && o.Guardrails.Action != GuardrailActionClamp {
+		return fmt.Errorf("the guardrail action %s is not supported, please use %s or %s", o.Guardrails.Action, GuardrailActionRefuse, GuardrailActionClamp)
+	}
+
+	for _, percent := range []*float64{
+		o.Guardrails.MaxCpuDecreasePercent, o.Guardrails.MaxMemoryDecreasePercent, o.Guardrails.MaxReplicasDecreasePercent,
+	} {
+		if percent != nil && (*percent < 0 || *percent > 100) {
+			return errors.New("the max decrease percentage should be between 0 and 100")
+		}
+	}
+
+	for _, percent := range []*float64{
+		o.Guardrails.MaxCpuIncreasePercent, o.Guardrails.MaxMemoryIncreasePercent, o.Guardrails.MaxReplicasIncreasePercent,
+	} {
+		if percent != nil && *percent < 0 {
+			return errors.New("the max increase percentage should not be negative")
+		}
+	}
+
+	var err error
+	if len(o.Guardrails.MinCpu) > 0 {
+		if o.minCpu, err = resource.ParseQuantity(o.Guardrails.MinCpu); err != nil {
+			return fmt.Errorf("invalid min cpu %s, %v", o.Guardrails.MinCpu, err)
+		}
+	}
+
+	if len(o.Guardrails.MinMemory) > 0 {
+		if o.minMemory, err = resource.ParseQuantity(o.Guardrails.MinMemory); err != nil {
+			return fmt.Errorf("invalid min memory %s, %v", o.Guardrails.MinMemory, err)
+		}
+	}
+
+	if o.Guardrails.MinMemoryLimitRatio != nil && (*o.Guardrails.MinMemoryLimitRatio < 0 || *o.Guardrails.MinMemoryLimitRatio > 1) {
+		return errors.New("the min memory limit ratio should be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Apply checks the RecommendedInfo against the current values of the live target. It returns the
+// RecommendedInfo to adopt, clamped when the action is clamp, and the violated guardrails.
+// A GuardrailError is returned when the action is refuse and any guardrail is violated.
+func (o *GuardrailOptions) Apply(live *unstructured.Unstructured, recommend *analysisv1alpha1.Recommendation) (string, []string, error) {
+	var recommendedInfo string
+	var violations []string
+	var err error
+
+	switch recommend.Spec.Type {
+	case "Resource":
+		recommendedInfo, violations, err = o.applyResource(live, recommend.Status.RecommendedInfo)
+	case "Replicas":
+		recommendedInfo, violations, err = o.applyReplicas(live, recommend.Status.RecommendedInfo)
+	default:
+		return recommend.Status.RecommendedInfo, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(violations) == 0 {
+		return recommend.Status.RecommendedInfo, nil, nil
+	}
+
+	if o.Guardrails.Action == GuardrailActionRefuse {
+		return "", violations, &GuardrailError{Violations: violations}
+	}
+
+	return recommendedInfo, violations, nil
+}
+
+func (o *GuardrailOptions) applyResource(live *unstructured.Unstructured, info string) (string, []string, error) {
+	var recommendInfo analysisv1alpha1.PatchResource
+	if err := json.Unmarshal([]byte(info), &recommendInfo); err != nil {
+		return "", nil, err
+	}
+
+	liveContainers, err := utils.WorkloadContainers(live)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var violations []string
+	for i := range recommendInfo.Spec.Template.Spec.Containers {
+		container := &recommendInfo.Spec.Template.Spec.Containers[i]
+		var current corev1.ResourceRequirements
+		for _, liveContainer := range liveContainers {
+			if liveContainer.Name == container.Name {
+				current = liveContainer.Resources
+			}
+		}
+
+		if recommended, exist := container.Resources.Requests[corev1.ResourceCPU]; exist {
+			currentCpu := current.Requests[corev1.ResourceCPU]
+			clamped, messages := checkQuantity(container.Name, "cpu", currentCpu, recommended,
+				o.Guardrails.MaxCpuDecreasePercent, o.Guardrails.MaxCpuIncreasePercent, o.minCpu, resource.DecimalSI)
+			container.Resources.Requests[corev1.ResourceCPU] = clamped
+			violations = append(violations, messages...)
+		}
+
+		if recommended, exist := container.Resources.Requests[corev1.ResourceMemory]; exist {
+			currentMemory := current.Requests[corev1.ResourceMemory]
+			clamped, messages := checkQuantity(container.Name, "memory", currentMemory, recommended,
+				o.Guardrails.MaxMemoryDecreasePercent, o.Guardrails.MaxMemoryIncreasePercent, o.minMemory, resource.BinarySI)
+			// the ratio of the limit only bounds the reductions, never above the current request
+			if limit, exist := current.Limits[corev1.ResourceMemory]; exist && o.Guardrails.MinMemoryLimitRatio != nil && recommended.Cmp(currentMemory) < 0 {
+				limitFloor := scaleQuantity(limit, *o.Guardrails.MinMemoryLimitRatio, true, resource.BinarySI)
+				if limitFloor.Cmp(currentMemory) > 0 {
+					limitFloor = currentMemory.DeepCopy()
+				}
+				if clamped.Cmp(limitFloor) < 0 {
+					messages = append(messages, fmt.Sprintf("container %s memory %s decreases below %.0f%% of the memory limit %s",
+						container.Name, recommended.String(), *o.Guardrails.MinMemoryLimitRatio*100, limit.String()))
+					clamped = limitFloor
+				}
+			}
+			container.Resources.Requests[corev1.ResourceMemory] = clamped
+			violations = append(violations, messages...)
+		}
+	}
+
+	content, err := json.Marshal(recommendInfo)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return string(content), violations, nil
+}
+
+func (o *GuardrailOptions) applyReplicas(live *unstructured.Unstructured, info string) (string, []string, error) {
+	recommended, err := utils.DecodeReplicasInfo(info)
+	if err != nil {
+		return "", nil, err
+	}
+
+	current, found, err := unstructured.NestedInt64(live.Object, "spec", "replicas")
+	if err != nil || !found || current == 0 {
+		return info, nil, nil
+	}
+
+	clamped, violations := o.checkReplicas("replicas", current, int64(recommended))
+
+	replicas := int32(clamped)
+	content, err := json.Marshal(analysisv1alpha1.PatchReplicas{Spec: analysisv1alpha1.PatchReplicasSpec{Replicas: &replicas}})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return string(content), violations, nil
+}
+
+// ApplyAutoscaling checks the min and max replicas proposed for an autoscaler against the current ones,
+// a zero current value is not checked, e.g. the max replicas of an EHPA to create. It returns the proposed
+// spec to adopt, clamped when the action is clamp, and the violated guardrails like Apply.
+func (o *GuardrailOptions) ApplyAutoscaling(currentMin, currentMax int64, proposed *analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation) (*analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation, []string, error) {
+	proposed = proposed.DeepCopy()
+
+	var violations []string
+	if proposed.MinReplicas != nil && currentMin > 0 {
+		clamped, messages := o.checkReplicas("min replicas", currentMin, int64(*proposed.MinReplicas))
+		minReplicas := int32(clamped)
+		proposed.MinReplicas = &minReplicas
+		violations = append(violations, messages...)
+	}
+	if proposed.MaxReplicas != nil && currentMax > 0 {
+		clamped, messages := o.checkReplicas("max replicas", currentMax, int64(*proposed.MaxReplicas))
+		maxReplicas := int32(clamped)
+		proposed.MaxReplicas = &maxReplicas
+		violations = append(violations, messages...)
+	}
+	// the min and max replicas are clamped apart, keep them in order
+	if proposed.MinReplicas != nil && proposed.MaxReplicas != nil && *proposed.MinReplicas > *proposed.MaxReplicas {
+		minReplicas := *proposed.MaxReplicas
+		proposed.MinReplicas = &minReplicas
+	}
+
+	if len(violations) > 0 && o.Guardrails.Action == GuardrailActionRefuse {
+		return nil, violations, &GuardrailError{Violations: violations}
+	}
+
+	return proposed, violations, nil
+}
+
+// checkReplicas returns the recommended replicas clamped into the guardrails and the violations
+func (o *GuardrailOptions) checkReplicas(name string, current, recommended int64) (int64, []string) {
+	var violations []string
+	clamped := recommended
+	if maxDecrease := o.Guardrails.MaxReplicasDecreasePercent; maxDecrease != nil {
+		floor := int64(math.Ceil(float64(current) * (1 - *maxDecrease/100)))
+		if clamped < floor {
+			violations = append(violations, fmt.Sprintf("%s %d decreases more than %.0f%% from %d", name, recommended, *maxDecrease, current))
+			clamped = floor
+		}
+	}
+	if maxIncrease := o.Guardrails.MaxReplicasIncreasePercent; maxIncrease != nil {
+		ceiling := int64(math.Floor(float64(current) * (1 + *maxIncrease/100)))
+		if clamped > ceiling {
+			violations = append(violations, fmt.Sprintf("%s %d increases more than %.0f%% from %d", name, recommended, *maxIncrease, current))
+			clamped = ceiling
+		}
+	}
+
+	return clamped, violations
+}
+
+// checkQuantity returns the recommended quantity clamped into the guardrails and the violations
+func checkQuantity(container, name string, current, recommended resource.Quantity, maxDecrease, maxIncrease *float64, minimum resource.Quantity, format resource.Format) (resource.Quantity, []string) {
+	var violations []string
+	clamped := recommended.DeepCopy()
+
+	if !current.IsZero() {
+		if maxDecrease != nil {
+			floor := scaleQuantity(current, 1-*maxDecrease/100, true, format)
+			if clamped.Cmp(floor) < 0 {
+				violations = append(violations, fmt.Sprintf("container %s %s %s decreases more than %.0f%% from %s", container, name, recommended.String(), *maxDecrease, current.String()))
+				clamped = floor
+			}
+		}
+		if maxIncrease != nil {
+			ceiling := scaleQuantity(current, 1+*maxIncrease/100, false, format)
+			if clamped.Cmp(ceiling) > 0 {
+				violations = append(violations, fmt.Sprintf("container %s %s %s increases more than %.0f%% from %s", container, name, recommended.String(), *maxIncrease, current.String()))
+				clamped = ceiling
+			}
+		}
+	}
+
+	if !minimum.IsZero() && clamped.Cmp(minimum) < 0 {
+		violations = append(violations, fmt.Sprintf("container %s %s %s is lower than the minimum %s", container, name, recommended.String(), minimum.String()))
+		clamped = minimum.DeepCopy()
+	}
+
+	return clamped, violations
+}
+
+// scaleQuantity multiplies the quantity by the factor, cpu is rounded to millicores and memory to bytes
+func scaleQuantity(quantity resource.Quantity, factor float64, roundUp bool, format resource.Format) resource.Quantity {
+	round := math.Floor
+	if roundUp {
+		round = math.Ceil
+	}
+
+	if format == resource.BinarySI {
+		return *resource.NewQuantity(int64(round(float64(quantity.Value())*factor)), format)
+	}
+
+	return *resource.NewMilliQuantity(int64(round(float64(quantity.MilliValue())*factor)), format)
+}
+
+func (o *GuardrailOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.ConfigFile, "guardrails-config", "", "", "Path of the guardrails config file, the flags override the values in the file")
+	cmd.Flags().StringVarP(&o.flagAction, "guardrail-action", "", GuardrailActionRefuse, "Action when a recommend violates the guardrails[refuse, clamp]")
+	cmd.Flags().Float64VarP(&o.flagMaxCpuDecreasePercent, "max-cpu-decrease", "", 0, "Maximum percentage a recommend may decrease the cpu request")
+	cmd.Flags().Float64VarP(&o.flagMaxCpuIncreasePercent, "max-cpu-increase", "", 0, "Maximum percentage a recommend may increase the cpu request")
+	cmd.Flags().Float64VarP(&o.flagMaxMemoryDecreasePercent, "max-memory-decrease", "", 0, "Maximum percentage a recommend may decrease the memory request")
+	cmd.Flags().Float64VarP(&o.flagMaxMemoryIncreasePercent, "max-memory-increase", "", 0, "Maximum percentage a recommend may increase the memory request")
+	cmd.Flags().Float64VarP(&o.flagMaxReplicasDecreasePercent, "max-replicas-decrease", "", 0, "Maximum percentage a recommend may decrease the replicas")
+	cmd.Flags().Float64VarP(&o.flagMaxReplicasIncreasePercent, "max-replicas-increase", "", 0, "Maximum percentage a recommend may increase the replicas")
+	cmd.Flags().StringVarP(&o.flagMinCpu, "min-cpu", "", "", "Minimum cpu request of every container")
+	cmd.Flags().StringVarP(&o.flagMinMemory, "min-memory", "", "", "Minimum memory request of every container")
+	cmd.Flags().Float64VarP(&o.flagMinMemoryLimitRatio, "min-memory-limit-ratio", "", 0, "Never reduce the memory request below this ratio of the current memory limit")
+}
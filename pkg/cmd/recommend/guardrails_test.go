@@ -0,0 +1,433 @@
+package recommend
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
+
+func newGuardrailOptions(t *testing.T, guardrails Guardrails) *GuardrailOptions {
+	if len(guardrails.Action) == 0 {
+		guardrails.Action = GuardrailActionRefuse
+	}
+	o := &GuardrailOptions{Guardrails: guardrails}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("invalid guardrails, %v", err)
+	}
+
+	return o
+}
+
+func newLiveDeployment(replicas interface{}) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{
+						"name": "app",
+						"resources": map[string]interface{}{
+							"requests": map[string]interface{}{"cpu": "1", "memory": "1Gi"},
+							"limits":   map[string]interface{}{"memory": "2Gi"},
+						},
+					},
+					map[string]interface{}{
+						"name": "sidecar",
+						"resources": map[string]interface{}{
+							"requests": map[string]interface{}{"cpu": "100m"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if replicas != nil {
+		spec["replicas"] = replicas
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec":       spec,
+	}}
+}
+
+func newRecommendation(recommendType analysisv1alpha1.AnalysisType, recommendedInfo string) *analysisv1alpha1.Recommendation {
+	recommend := &analysisv1alpha1.Recommendation{}
+	recommend.Name = "web-" + string(recommendType)
+	recommend.Spec.Type = recommendType
+	recommend.Status.RecommendedInfo = recommendedInfo
+
+	return recommend
+}
+
+func TestCheckQuantity(t *testing.T) {
+	testCases := []struct {
+		name           string
+		current        string
+		recommended    string
+		maxDecrease    *float64
+		maxIncrease    *float64
+		minimum        string
+		expected       string
+		expectedErrors int
+	}{
+		{name: "no guardrails", current: "1", recommended: "200m", expected: "200m"},
+		{name: "decrease at the limit", current: "1", recommended: "500m", maxDecrease: float64Ptr(50), expected: "500m"},
+		{name: "decrease over the limit", current: "1", recommended: "499m", maxDecrease: float64Ptr(50), expected: "500m", expectedErrors: 1},
+		{name: "no decrease allowed", current: "1", recommended: "999m", maxDecrease: float64Ptr(0), expected: "1", expectedErrors: 1},
+		{name: "full decrease allowed", current: "1", recommended: "1m", maxDecrease: float64Ptr(100), expected: "1m"},
+		{name: "increase at the limit", current: "1", recommended: "1500m", maxIncrease: float64Ptr(50), expected: "1500m"},
+		{name: "increase over the limit", current: "1", recommended: "1501m", maxIncrease: float64Ptr(50), expected: "1500m", expectedErrors: 1},
+		{name: "zero recommended value", current: "1", recommended: "0", maxDecrease: float64Ptr(50), expected: "500m", expectedErrors: 1},
+		{name: "zero current is not limited", current: "0", recommended: "100m", maxDecrease: float64Ptr(50), maxIncrease: float64Ptr(50), expected: "100m"},
+		{name: "at the minimum", current: "1", recommended: "250m", minimum: "250m", expected: "250m"},
+		{name: "below the minimum", current: "1", recommended: "100m", minimum: "250m", expected: "250m", expectedErrors: 1},
+		{name: "clamped decrease below the minimum", current: "1", recommended: "100m", maxDecrease: float64Ptr(50), minimum: "600m", expected: "600m", expectedErrors: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var minimum resource.Quantity
+			if len(tc.minimum) > 0 {
+				minimum = resource.MustParse(tc.minimum)
+			}
+
+			clamped, violations := checkQuantity("app", "cpu", resource.MustParse(tc.current), resource.MustParse(tc.recommended),
+				tc.maxDecrease, tc.maxIncrease, minimum, resource.DecimalSI)
+			if expected := resource.MustParse(tc.expected); clamped.Cmp(expected) != 0 {
+				t.Errorf("expected %s, got %s", expected.String(), clamped.String())
+			}
+			if len(violations) != tc.expectedErrors {
+				t.Errorf("expected %d violations, got %v", tc.expectedErrors, violations)
+			}
+		})
+	}
+}
+
+func TestApplyResource(t *testing.T) {
+	testCases := []struct {
+		name            string
+		guardrails      Guardrails
+		recommendedInfo string
+		// the expected requests of the containers, an empty value means the request is not recommended
+		expectedCpu       map[string]string
+		expectedMemory    map[string]string
+		expectedErrors    int
+		expectedRefusal   bool
+		expectedUnchanged bool
+	}{
+		{
+			name:              "within the guardrails",
+			guardrails:        Guardrails{MaxCpuDecreasePercent: float64Ptr(50)},
+			recommendedInfo:   `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"600m","memory":"512Mi"}}}]}}}}`,
+			expectedCpu:       map[string]string{"app": "600m"},
+			expectedMemory:    map[string]string{"app": "512Mi"},
+			expectedUnchanged: true,
+		},
+		{
+			name:            "refused",
+			guardrails:      Guardrails{MaxCpuDecreasePercent: float64Ptr(50)},
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"400m"}}}]}}}}`,
+			expectedErrors:  1,
+			expectedRefusal: true,
+		},
+		{
+			name:            "clamped",
+			guardrails:      Guardrails{Action: GuardrailActionClamp, MaxCpuDecreasePercent: float64Ptr(50)},
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"400m"}}},{"name":"sidecar","resources":{"requests":{"cpu":"40m"}}}]}}}}`,
+			expectedCpu:     map[string]string{"app": "500m", "sidecar": "50m"},
+			expectedMemory:  map[string]string{"app": "", "sidecar": ""},
+			expectedErrors:  2,
+		},
+		{
+			name:            "unmatched container is checked against the minimum only",
+			guardrails:      Guardrails{Action: GuardrailActionClamp, MaxCpuDecreasePercent: float64Ptr(50), MinCpu: "100m"},
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"other","resources":{"requests":{"cpu":"10m"}}}]}}}}`,
+			expectedCpu:     map[string]string{"other": "100m"},
+			expectedErrors:  1,
+		},
+		{
+			name:              "missing recommended memory is not checked",
+			guardrails:        Guardrails{MinMemory: "2Gi"},
+			recommendedInfo:   `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"1"}}}]}}}}`,
+			expectedCpu:       map[string]string{"app": "1"},
+			expectedMemory:    map[string]string{"app": ""},
+			expectedUnchanged: true,
+		},
+		{
+			name:            "reduction below the memory limit ratio",
+			guardrails:      Guardrails{Action: GuardrailActionClamp, MinMemoryLimitRatio: float64Ptr(0.25)},
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"memory":"256Mi"}}}]}}}}`,
+			expectedMemory:  map[string]string{"app": "512Mi"},
+			expectedErrors:  1,
+		},
+		{
+			name:              "reduction above the memory limit ratio",
+			guardrails:        Guardrails{MinMemoryLimitRatio: float64Ptr(0.25)},
+			recommendedInfo:   `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"memory":"768Mi"}}}]}}}}`,
+			expectedMemory:    map[string]string{"app": "768Mi"},
+			expectedUnchanged: true,
+		},
+		{
+			name:            "memory limit ratio floor is capped at the current request",
+			guardrails:      Guardrails{Action: GuardrailActionClamp, MinMemoryLimitRatio: float64Ptr(0.75)},
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"memory":"900Mi"}}}]}}}}`,
+			expectedMemory:  map[string]string{"app": "1Gi"},
+			expectedErrors:  1,
+		},
+		{
+			name:              "memory limit ratio doesn't bound increases",
+			guardrails:        Guardrails{MinMemoryLimitRatio: float64Ptr(0.75)},
+			recommendedInfo:   `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"memory":"1200Mi"}}}]}}}}`,
+			expectedMemory:    map[string]string{"app": "1200Mi"},
+			expectedUnchanged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newGuardrailOptions(t, tc.guardrails)
+			recommendedInfo, violations, err := o.Apply(newLiveDeployment(int64(2)), newRecommendation(analysisv1alpha1.AnalysisTypeResource, tc.recommendedInfo))
+			if len(violations) != tc.expectedErrors {
+				t.Errorf("expected %d violations, got %v", tc.expectedErrors, violations)
+			}
+
+			var guardrailErr *GuardrailError
+			if tc.expectedRefusal {
+				if !errors.As(err, &guardrailErr) {
+					t.Fatalf("expected the recommendation refused, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tc.expectedUnchanged && recommendedInfo != tc.recommendedInfo {
+				t.Errorf("expected the recommended info unchanged, got %s", recommendedInfo)
+			}
+
+			requests, err := utils.DecodeResourceInfo(recommendedInfo)
+			if err != nil {
+				t.Fatalf("invalid recommended info %s, %v", recommendedInfo, err)
+			}
+			for _, expected := range []struct {
+				values   map[string]string
+				quantity func(utils.ContainerRequest) resource.Quantity
+			}{
+				{tc.expectedCpu, func(request utils.ContainerRequest) resource.Quantity { return request.Cpu }},
+				{tc.expectedMemory, func(request utils.ContainerRequest) resource.Quantity { return request.Memory }},
+			} {
+				for container, value := range expected.values {
+					found := false
+					for _, request := range requests {
+						if request.Name != container {
+							continue
+						}
+						found = true
+						quantity := expected.quantity(request)
+						if len(value) == 0 {
+							if !quantity.IsZero() {
+								t.Errorf("expected no request of container %s, got %s", container, quantity.String())
+							}
+							continue
+						}
+						if quantity.Cmp(resource.MustParse(value)) != 0 {
+							t.Errorf("expected the request of container %s to be %s, got %s", container, value, quantity.String())
+						}
+					}
+					if !found {
+						t.Errorf("container %s not found in %s", container, recommendedInfo)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestApplyReplicas(t *testing.T) {
+	testCases := []struct {
+		name              string
+		guardrails        Guardrails
+		current           interface{}
+		recommended       string
+		expected          int32
+		expectedErrors    int
+		expectedRefusal   bool
+		expectedUnchanged bool
+	}{
+		{name: "decrease at the limit", guardrails: Guardrails{MaxReplicasDecreasePercent: float64Ptr(50)}, current: int64(4), recommended: `{"spec":{"replicas":2}}`, expected: 2, expectedUnchanged: true},
+		{name: "decrease over the limit refused", guardrails: Guardrails{MaxReplicasDecreasePercent: float64Ptr(50)}, current: int64(4), recommended: `{"spec":{"replicas":1}}`, expectedErrors: 1, expectedRefusal: true},
+		{name: "decrease over the limit clamped", guardrails: Guardrails{Action: GuardrailActionClamp, MaxReplicasDecreasePercent: float64Ptr(50)}, current: int64(5), recommended: `{"spec":{"replicas":1}}`, expected: 3, expectedErrors: 1},
+		{name: "increase at the limit", guardrails: Guardrails{MaxReplicasIncreasePercent: float64Ptr(50)}, current: int64(4), recommended: `{"spec":{"replicas":6}}`, expected: 6, expectedUnchanged: true},
+		{name: "increase over the limit clamped", guardrails: Guardrails{Action: GuardrailActionClamp, MaxReplicasIncreasePercent: float64Ptr(50)}, current: int64(4), recommended: `{"spec":{"replicas":7}}`, expected: 6, expectedErrors: 1},
+		{name: "missing current replicas are not checked", guardrails: Guardrails{MaxReplicasDecreasePercent: float64Ptr(0)}, recommended: `{"spec":{"replicas":1}}`, expected: 1, expectedUnchanged: true},
+		{name: "zero current replicas are not checked", guardrails: Guardrails{MaxReplicasIncreasePercent: float64Ptr(0)}, current: int64(0), recommended: `{"spec":{"replicas":3}}`, expected: 3, expectedUnchanged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newGuardrailOptions(t, tc.guardrails)
+			recommendedInfo, violations, err := o.Apply(newLiveDeployment(tc.current), newRecommendation(analysisv1alpha1.AnalysisTypeReplicas, tc.recommended))
+			if len(violations) != tc.expectedErrors {
+				t.Errorf("expected %d violations, got %v", tc.expectedErrors, violations)
+			}
+
+			var guardrailErr *GuardrailError
+			if tc.expectedRefusal {
+				if !errors.As(err, &guardrailErr) {
+					t.Fatalf("expected the recommendation refused, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tc.expectedUnchanged && recommendedInfo != tc.recommended {
+				t.Errorf("expected the recommended info unchanged, got %s", recommendedInfo)
+			}
+
+			replicas, err := utils.DecodeReplicasInfo(recommendedInfo)
+			if err != nil {
+				t.Fatalf("invalid recommended info %s, %v", recommendedInfo, err)
+			}
+			if replicas != tc.expected {
+				t.Errorf("expected %d replicas, got %d", tc.expected, replicas)
+			}
+		})
+	}
+}
+
+func TestApplyAutoscaling(t *testing.T) {
+	testCases := []struct {
+		name            string
+		guardrails      Guardrails
+		currentMin      int64
+		currentMax      int64
+		proposedMin     *int32
+		proposedMax     *int32
+		expectedMin     *int32
+		expectedMax     *int32
+		expectedErrors  int
+		expectedRefusal bool
+	}{
+		{
+			name:        "within the guardrails",
+			guardrails:  Guardrails{MaxReplicasDecreasePercent: float64Ptr(50)},
+			currentMin:  4,
+			currentMax:  10,
+			proposedMin: int32Ptr(2),
+			proposedMax: int32Ptr(5),
+			expectedMin: int32Ptr(2),
+			expectedMax: int32Ptr(5),
+		},
+		{
+			name:            "refused",
+			guardrails:      Guardrails{MaxReplicasDecreasePercent: float64Ptr(50)},
+			currentMin:      4,
+			currentMax:      10,
+			proposedMin:     int32Ptr(1),
+			proposedMax:     int32Ptr(3),
+			expectedErrors:  2,
+			expectedRefusal: true,
+		},
+		{
+			name:           "clamped",
+			guardrails:     Guardrails{Action: GuardrailActionClamp, MaxReplicasDecreasePercent: float64Ptr(50)},
+			currentMin:     4,
+			currentMax:     10,
+			proposedMin:    int32Ptr(1),
+			proposedMax:    int32Ptr(3),
+			expectedMin:    int32Ptr(2),
+			expectedMax:    int32Ptr(5),
+			expectedErrors: 2,
+		},
+		{
+			name:           "clamped min replicas kept below the max replicas",
+			guardrails:     Guardrails{Action: GuardrailActionClamp, MaxReplicasDecreasePercent: float64Ptr(0)},
+			currentMin:     4,
+			currentMax:     4,
+			proposedMin:    int32Ptr(6),
+			proposedMax:    int32Ptr(2),
+			expectedMin:    int32Ptr(4),
+			expectedMax:    int32Ptr(4),
+			expectedErrors: 1,
+		},
+		{
+			name:        "zero current max replicas are not checked",
+			guardrails:  Guardrails{MaxReplicasIncreasePercent: float64Ptr(0)},
+			currentMin:  1,
+			proposedMin: int32Ptr(1),
+			proposedMax: int32Ptr(8),
+			expectedMin: int32Ptr(1),
+			expectedMax: int32Ptr(8),
+		},
+		{
+			name:        "missing proposed min replicas are not set",
+			guardrails:  Guardrails{MaxReplicasIncreasePercent: float64Ptr(0)},
+			currentMin:  2,
+			currentMax:  8,
+			proposedMax: int32Ptr(8),
+			expectedMax: int32Ptr(8),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newGuardrailOptions(t, tc.guardrails)
+			proposed := &analysisv1alpha1.EffectiveHorizontalPodAutoscalerRecommendation{MinReplicas: tc.proposedMin, MaxReplicas: tc.proposedMax}
+			applied, violations, err := o.ApplyAutoscaling(tc.currentMin, tc.currentMax, proposed)
+			if len(violations) != tc.expectedErrors {
+				t.Errorf("expected %d violations, got %v", tc.expectedErrors, violations)
+			}
+
+			var guardrailErr *GuardrailError
+			if tc.expectedRefusal {
+				if !errors.As(err, &guardrailErr) {
+					t.Fatalf("expected the recommendation refused, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			for _, replicas := range []struct {
+				name     string
+				expected *int32
+				applied  *int32
+			}{
+				{"min replicas", tc.expectedMin, applied.MinReplicas},
+				{"max replicas", tc.expectedMax, applied.MaxReplicas},
+			} {
+				switch {
+				case replicas.expected == nil && replicas.applied != nil:
+					t.Errorf("expected no %s, got %d", replicas.name, *replicas.applied)
+				case replicas.expected != nil && replicas.applied == nil:
+					t.Errorf("expected %s %d, got none", replicas.name, *replicas.expected)
+				case replicas.expected != nil && *replicas.expected != *replicas.applied:
+					t.Errorf("expected %s %d, got %d", replicas.name, *replicas.expected, *replicas.applied)
+				}
+			}
+
+			// the proposed spec is not modified in place
+			if tc.proposedMin != nil && proposed.MinReplicas != tc.proposedMin {
+				t.Errorf("the proposed min replicas are modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/klog/v2"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+const (
+	FreshnessFresh   = "Fresh"
+	FreshnessStale   = "Stale"
+	FreshnessPending = "Pending"
+	FreshnessUnknown = "Unknown"
+
+	DriftInSync  = "InSync"
+	DriftDrifted = "Drifted"
+	DriftUnknown = "Unknown"
+	// DriftNone is for the recommendations that don't capture the target spec, e.g. IdleNode, or when the drift isn't checked
+	DriftNone = "-"
+)
+
+const (
+	MissionStatusSucceeded = "Succeeded"
+	MissionStatusFailed    = "Failed"
+	MissionStatusPending   = "Pending"
+
+	// the message of the missions run successfully
+	missionSuccessMessage = "Success"
+
+	// StaleRunIntervals is the number of run intervals a recommendation is not updated before it is stale,
+	// a single interval is not enough as the rule may be running right now.
+	StaleRunIntervals = 2
+)
+
+// RecommendationHealth tells whether a recommendation can be trusted
+type RecommendationHealth struct {
+	// Freshness compares the time since the last update with the run interval of the rule
+	Freshness   string
+	Age         time.Duration
+	RunInterval time.Duration
+	// Drift tells whether the target changed since the CurrentInfo was captured
+	Drift  string
+	Drifts []string
+	// Message is the error of the last run of the rule for the recommendation
+	Message string
+}
+
+// HealthChecker checks the recommendations against their rules and targets,
+// the rules, the targets and the results are cached for the lifetime of the checker.
+// The drift is only checked when enabled, as it reads the targets of the recommendations.
+type HealthChecker struct {
+	commonOptions *options.CommonOptions
+	now           time.Time
+	drift         bool
+	rules         map[string]*analysisv1alpha1.RecommendationRule
+	// targets are listed once per api version, kind and namespace, keyed by name
+	targets map[string]map[string]*unstructured.Unstructured
+	results map[string]RecommendationHealth
+}
+
+func NewHealthChecker(commonOptions *options.CommonOptions, drift bool) *HealthChecker {
+	return &HealthChecker{
+		commonOptions: commonOptions,
+		now:           time.Now(),
+		drift:         drift,
+		rules:         map[string]*analysisv1alpha1.RecommendationRule{},
+		targets:       map[string]map[string]*unstructured.Unstructured{},
+		results:       map[string]RecommendationHealth{},
+	}
+}
+
+// Check returns the freshness and the drift of the recommendation
+func (c *HealthChecker) Check(recommendation *analysisv1alpha1.Recommendation) RecommendationHealth {
+	// the version is part of the key, so that a recommendation updated in a watch is checked again
+	key := string(recommendation.UID) + "/" + recommendation.ResourceVersion
+	if health, exist := c.results[key]; exist && len(recommendation.UID) > 0 {
+		return health
+	}
+
+	health := RecommendationHealth{
+		Freshness: FreshnessPending,
+	}
+
+	rule := c.getRule(recommendation.Labels[RecommendationRuleNameLabel])
+	if rule != nil {
+		if runInterval, err := time.ParseDuration(rule.Spec.RunInterval); err == nil {
+			health.RunInterval = runInterval
+		}
+		for _, mission := range rule.Status.Recommendations {
+			if mission.Name == recommendation.Name && mission.Namespace == recommendation.Namespace {
+				health.Message = MissionMessage(mission)
+			}
+		}
+	}
+	if len(health.Message) == 0 {
+		for _, condition := range recommendation.Status.Conditions {
+			if condition.Status == metav1.ConditionFalse {
+				health.Message = condition.Message
+			}
+		}
+	}
+
+	// a recommendation never updated is pending until it is stale like the others
+	health.Age = c.now.Sub(LastUpdateTime(recommendation).Time)
+	updated := recommendation.Status.LastUpdateTime != nil
+	switch {
+	case health.RunInterval == 0 && updated:
+		health.Freshness = FreshnessUnknown
+	case health.RunInterval > 0 && IsStale(recommendation, health.RunInterval, c.now):
+		health.Freshness = FreshnessStale
+	case updated:
+		health.Freshness = FreshnessFresh
+	}
+
+	health.Drift = DriftNone
+	if c.drift {
+		health.Drift, health.Drifts = c.checkDrift(recommendation)
+	}
+	c.results[key] = health
+
+	return health
+}
+
+// checkDrift compares the CurrentInfo captured by the recommendation with the live target
+func (c *HealthChecker) checkDrift(recommendation *analysisv1alpha1.Recommendation) (string, []string) {
+	recommendType := string(recommendation.Spec.Type)
+	if recommendType != analysisv1alpha1.ResourceRecommender && recommendType != analysisv1alpha1.ReplicasRecommender {
+		return DriftNone, nil
+	}
+	if len(recommendation.Status.CurrentInfo) == 0 {
+		return DriftUnknown, nil
+	}
+
+	targets, err := c.getTargets(recommendation.Spec.TargetRef)
+	if err != nil {
+		return DriftUnknown, nil
+	}
+	live, exist := targets[recommendation.Spec.TargetRef.Name]
+	if !exist {
+		return DriftDrifted, []string{"the target no longer exists"}
+	}
+
+	var drifts []string
+	if recommendType == analysisv1alpha1.ReplicasRecommender {
+		captured, err := utils.DecodeReplicasInfo(recommendation.Status.CurrentInfo)
+		if err != nil {
+			return DriftUnknown, nil
+		}
+		if replicas := utils.WorkloadReplicas(live); replicas != captured {
+			drifts = append(drifts, fmt.Sprintf("replicas %d -> %d", captured, replicas))
+		}
+	} else {
+		captured, err := utils.DecodeResourceInfo(recommendation.Status.CurrentInfo)
+		if err != nil {
+			return DriftUnknown, nil
+		}
+		containers, err := utils.WorkloadContainers(live)
+		if err != nil {
+			return DriftUnknown, nil
+		}
+		drifts = resourceDrifts(captured, containers)
+	}
+
+	if len(drifts) > 0 {
+		return DriftDrifted, drifts
+	}
+
+	return DriftInSync, nil
+}
+
+// resourceDrifts lists the container requests changed since they were captured
+func resourceDrifts(captured []utils.ContainerRequest, containers []corev1.Container) []string {
+	var drifts []string
+
+	liveMap := map[string]corev1.Container{}
+	for _, container := range containers {
+		liveMap[container.Name] = container
+	}
+
+	capturedNames := map[string]bool{}
+	for _, request := range captured {
+		capturedNames[request.Name] = true
+		container, exist := liveMap[request.Name]
+		if !exist {
+			drifts = append(drifts, fmt.Sprintf("container %s removed", request.Name))
+			continue
+		}
+		if cpu := container.Resources.Requests.Cpu(); cpu.Cmp(request.Cpu) != 0 {
+			drifts = append(drifts, fmt.Sprintf("%s cpu %s -> %s", request.Name, request.Cpu.String(), cpu.String()))
+		}
+		if memory := container.Resources.Requests.Memory(); memory.Cmp(request.Memory) != 0 {
+			drifts = append(drifts, fmt.Sprintf("%s memory %s -> %s", request.Name, request.Memory.String(), memory.String()))
+		}
+	}
+
+	for _, container := range containers {
+		if !capturedNames[container.Name] {
+			drifts = append(drifts, fmt.Sprintf("container %s added", container.Name))
+		}
+	}
+
+	return drifts
+}
+
+// getTargets lists the targets of the same api version and kind in the namespace of the target,
+// a single list serves all the recommendations of a rule instead of a get for each of them.
+func (c *HealthChecker) getTargets(targetRef corev1.ObjectReference) (map[string]*unstructured.Unstructured, error) {
+	key := targetRef.APIVersion + "/" + targetRef.Kind + "/" + targetRef.Namespace
+	if targets, exist := c.targets[key]; exist {
+		if targets == nil {
+			return nil, errors.New("failed to list the targets")
+		}
+		return targets, nil
+	}
+	// the failures are cached as well, so that they are only reported once
+	c.targets[key] = nil
+
+	gvr, err := utils.GetGroupVersionResource(c.commonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		klog.Warningf("Failed to get the resource of %s %s, %v.", targetRef.APIVersion, targetRef.Kind, err)
+		return nil, err
+	}
+	list, err := c.commonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Warningf("Failed to list %s in %s, %v.", targetRef.Kind, targetRef.Namespace, err)
+		return nil, err
+	}
+
+	targets := map[string]*unstructured.Unstructured{}
+	for i := range list.Items {
+		targets[list.Items[i].GetName()] = &list.Items[i]
+	}
+	c.targets[key] = targets
+
+	return targets, nil
+}
+
+// getRule returns nil when the rule doesn't exist anymore, the misses are cached as well
+func (c *HealthChecker) getRule(name string) *analysisv1alpha1.RecommendationRule {
+	if len(name) == 0 {
+		return nil
+	}
+	if rule, exist := c.rules[name]; exist {
+		return rule
+	}
+
+	rule, err := c.commonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Warningf("Failed to get recommendation rule %s, %v.", name, err)
+		}
+		rule = nil
+	}
+	c.rules[name] = rule
+
+	return rule
+}
+
+// MissionStatus returns whether the recommendation mission of a target succeeded, failed or hasn't run yet
+func MissionStatus(mission analysisv1alpha1.RecommendationMission) string {
+	switch {
+	case mission.LastStartTime == nil && len(mission.Message) == 0:
+		return MissionStatusPending
+	case mission.Message == missionSuccessMessage:
+		return MissionStatusSucceeded
+	case len(mission.Message) == 0:
+		return MissionStatusPending
+	default:
+		return MissionStatusFailed
+	}
+}
+
+// MissionMessage returns the message of the failed mission, the message of the other missions is empty
+func MissionMessage(mission analysisv1alpha1.RecommendationMission) string {
+	if MissionStatus(mission) != MissionStatusFailed {
+		return ""
+	}
+
+	return mission.Message
+}
+
+// LastUpdateTime returns the time the recommendation was last updated, or created if it was never updated
+func LastUpdateTime(recommendation *analysisv1alpha1.Recommendation) metav1.Time {
+	if recommendation.Status.LastUpdateTime != nil {
+		return *recommendation.Status.LastUpdateTime
+	}
+
+	return recommendation.CreationTimestamp
+}
+
+// IsStale tells whether the recommendation is not updated for StaleRunIntervals run intervals of the rule
+func IsStale(recommendation *analysisv1alpha1.Recommendation, runInterval time.Duration, now time.Time) bool {
+	return now.Sub(LastUpdateTime(recommendation).Time) > StaleRunIntervals*runInterval
+}
+
+// StaleRecommendations returns the stale recommendations of the rule
+func StaleRecommendations(recommendationRule *analysisv1alpha1.RecommendationRule, recommendations []analysisv1alpha1.Recommendation, now time.Time) []analysisv1alpha1.Recommendation {
+	runInterval, err := time.ParseDuration(recommendationRule.Spec.RunInterval)
+	if err != nil {
+		return nil
+	}
+
+	var stale []analysisv1alpha1.Recommendation
+	for i := range recommendations {
+		if IsStale(&recommendations[i], runInterval, now) {
+			stale = append(stale, recommendations[i])
+		}
+	}
+
+	return stale
+}
+
+// printFreshness prints the freshness with the time since the last update and the run interval, e.g. Stale (50h/24h)
+func printFreshness(health RecommendationHealth) string {
+	switch health.Freshness {
+	case FreshnessPending:
+		return health.Freshness
+	case FreshnessUnknown:
+		return fmt.Sprintf("%s (%s)", health.Freshness, duration.HumanDuration(health.Age))
+	}
+
+	return fmt.Sprintf("%s (%s/%s)", health.Freshness, duration.HumanDuration(health.Age), duration.HumanDuration(health.RunInterval))
+}
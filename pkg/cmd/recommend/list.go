@@ -2,15 +2,16 @@ package recommend
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 
 	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
@@ -29,6 +30,24 @@ var (
 
 # view Resource type recommend result with kube-system namespace
 %[1]s recommend list --namespace kube-system --type Resource
+
+# view recommend result with the decoded container requests and whether the targets drifted
+%[1]s recommend list --namespace kube-system -o wide
+
+# output recommend result as yaml
+%[1]s recommend list --namespace kube-system -o yaml
+
+# view the recommend result not updated for two run intervals of its rule
+%[1]s recommend list --namespace kube-system --stale-only
+
+# view the recommend result whose target changed since it was computed
+%[1]s recommend list --namespace kube-system --drifted-only -o wide
+
+# watch the changes of the recommend result after listing it
+%[1]s recommend list --namespace kube-system -w
+
+# view the recommend result saved by 'kubectl crane snapshot' without access to the cluster
+%[1]s recommend list --from-file snapshot.yaml -A
 `
 )
 
@@ -42,19 +61,26 @@ const (
 	RunNumberAnnotation                  = "analysis.crane.io/run-number"
 )
 
+// watchHealthRefreshInterval is how long the rules and the targets read to check the health
+// are cached when watching the recommendations
+const watchHealthRefreshInterval = time.Minute
+
 type RecommendListOptions struct {
 	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
+	FilterOptions *RecommendFilterOptions
 
-	Name       string
-	Type       string
-	TargetKind string
-	TargetName string
-	RuleName   string
+	Name        string
+	Watch       bool
+	StaleOnly   bool
+	DriftedOnly bool
 }
 
 func NewRecommendListOptions() *RecommendListOptions {
 	return &RecommendListOptions{
 		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
+		FilterOptions: NewRecommendFilterOptions(),
 	}
 }
 
@@ -82,6 +108,8 @@ func NewCmdRecommendList() *cobra.Command {
 		},
 	}
 	o.CommonOptions.AddCommonFlag(command)
+	o.CommonOptions.AddFromFileFlag(command)
+	o.PrintOptions.AddPrintFlags(command)
 	o.AddFlags(command)
 
 	return command
@@ -92,16 +120,16 @@ func (o *RecommendListOptions) Validate() error {
 		return err
 	}
 
-	if len(o.Type) > 0 {
-		typeExist := false
-		for _, recommenderType := range analysisv1alpha1.AllRecommenderType {
-			if recommenderType == o.Type {
-				typeExist = true
-			}
-		}
-		if !typeExist {
-			return fmt.Errorf("the recommender type not supported %s", o.Type)
-		}
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if o.Watch && len(o.CommonOptions.FromFile) > 0 {
+		return errors.New("--watch can't be used with --from-file")
 	}
 
 	return nil
@@ -121,45 +149,17 @@ func (o *RecommendListOptions) Run() error {
 		query.Filters[utils.FieldName] = utils.Value(o.Name)
 	}
 
-	if len(o.Type) > 0 {
-		query.LabelSelector[RecommendationRuleRecommenderLabel] = o.Type
-	}
-
-	if len(o.TargetKind) > 0 {
-		query.LabelSelector[RecommendationRuleTargetKindLabel] = o.TargetKind
+	if o.Watch {
+		return o.watch(query)
 	}
 
-	if len(o.TargetName) > 0 {
-		query.LabelSelector[RecommendationRuleTargetNameLabel] = o.TargetName
-	}
-
-	if len(o.RuleName) > 0 {
-		query.LabelSelector[RecommendationRuleNameLabel] = o.RuleName
-	}
-
-	namespace := ""
-	if len(*o.CommonOptions.ConfigFlags.Namespace) > 0 {
-		namespace = *o.CommonOptions.ConfigFlags.Namespace
-	}
-
-	selector := ""
-	for label, value := range query.LabelSelector {
-		selector += label + "=" + value + ","
-	}
-	// remove the last ","
-	if len(selector) > 0 {
-		selector = selector[:len(selector)-1]
-	}
-	listOptions := metav1.ListOptions{
-		LabelSelector: selector,
-	}
-	recommendResult, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(namespace).List(context.TODO(), listOptions)
+	recommendResult, err := o.FilterOptions.ListRecommendations(o.CommonOptions)
 	if err != nil {
-		klog.Errorf("Failed to get recommend result, %v.", err)
 		return err
 	}
+	checker := NewHealthChecker(o.CommonOptions, o.checkDrift())
 	var recommendations []analysisv1alpha1.Recommendation
-	for _, recommendation := range recommendResult.Items {
+	for _, recommendation := range recommendResult {
 		selected := true
 		for field, value := range query.Filters {
 			if !utils.ObjectMetaFilter(recommendation.ObjectMeta, utils.Filter{Field: field, Value: value}) {
@@ -168,23 +168,91 @@ func (o *RecommendListOptions) Run() error {
 			}
 		}
 
-		if selected {
+		if selected && o.selectedByHealth(checker, &recommendation) {
 			recommendations = append(recommendations, recommendation)
 		}
 	}
 
-	RenderTable(recommendations, o.CommonOptions.Out)
+	return PrintRecommendations(recommendations, o.PrintOptions, checker, o.CommonOptions.Out)
+}
+
+// checkDrift reads the targets only when the drift is printed or filtered
+func (o *RecommendListOptions) checkDrift() bool {
+	return o.PrintOptions.IsWide() || o.DriftedOnly
+}
+
+// selectedByHealth filters the recommendations by --stale-only and --drifted-only
+func (o *RecommendListOptions) selectedByHealth(checker *HealthChecker, recommendation *analysisv1alpha1.Recommendation) bool {
+	if !o.StaleOnly && !o.DriftedOnly {
+		return true
+	}
+
+	health := checker.Check(recommendation)
+	if o.StaleOnly && health.Freshness != FreshnessStale {
+		return false
+	}
+	if o.DriftedOnly && health.Drift != DriftDrifted {
+		return false
+	}
+
+	return true
+}
+
+// watch prints the recommendations and then their changes one by one until interrupted
+func (o *RecommendListOptions) watch(query *utils.Query) error {
+	wide := o.PrintOptions.IsWide()
+	rowPrinter := utils.NewRowPrinter(o.CommonOptions.Out, append(table.Row{"EVENT"}, tableHeader(wide)...))
+
+	checker := NewHealthChecker(o.CommonOptions, o.checkDrift())
+	utils.WatchUntilInterrupted(context.TODO(), o.FilterOptions.ListWatch(o.CommonOptions), &analysisv1alpha1.Recommendation{}, func(eventType string, obj interface{}) {
+		recommendation, ok := obj.(*analysisv1alpha1.Recommendation)
+		if !ok {
+			return
+		}
+		for field, value := range query.Filters {
+			if !utils.ObjectMetaFilter(recommendation.ObjectMeta, utils.Filter{Field: field, Value: value}) {
+				return
+			}
+		}
+
+		// the rules and the targets may have changed, refresh them now and then instead of on every event
+		if time.Since(checker.now) > watchHealthRefreshInterval {
+			checker = NewHealthChecker(o.CommonOptions, o.checkDrift())
+		}
+		if !o.selectedByHealth(checker, recommendation) {
+			return
+		}
+
+		var err error
+		if o.PrintOptions.IsTable() {
+			err = rowPrinter.PrintRow(append(table.Row{eventType}, tableRow(*recommendation, checker.Check(recommendation), wide)...))
+		} else {
+			err = o.PrintOptions.PrintObj(recommendation, o.CommonOptions.Out)
+		}
+		if err != nil {
+			klog.Errorf("Failed to print recommendation %s, %v.", recommendation.Name, err)
+		}
+	})
 
 	return nil
 }
 
-func RenderTable(recommendations []analysisv1alpha1.Recommendation, out io.Writer) {
+// PrintRecommendations prints the recommendations with the format selected by -o,
+// the recommendations are rendered as a table with their health by default.
+func PrintRecommendations(recommendations []analysisv1alpha1.Recommendation, printOptions *options.PrintOptions, checker *HealthChecker, out io.Writer) error {
+	if printOptions.IsTable() {
+		RenderTable(recommendations, checker, out, printOptions.IsWide())
+		return nil
+	}
+
+	return printOptions.PrintObj(&analysisv1alpha1.RecommendationList{Items: recommendations}, out)
+}
+
+func RenderTable(recommendations []analysisv1alpha1.Recommendation, checker *HealthChecker, out io.Writer, wide bool) {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	t.SetOutputMirror(out)
-	header := table.Row{}
-	header = append(header, table.Row{"NAME", "NAMESPACE", "TYPE", "TARGET NAME", "TARGET NAMESPACE", "TARGET KIND", "CURRENT RESOURCE", "RECOMMEND RESOURCE", "ACTION", "CREATED TIME", "UPDATED TIME"}...)
-	t.AppendHeader(header)
+	t.AppendHeader(tableHeader(wide))
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{
 			Name:        "NAME",
@@ -198,77 +266,130 @@ func RenderTable(recommendations []analysisv1alpha1.Recommendation, out io.Write
 	})
 
 	for _, recommendation := range recommendations {
-		row := table.Row{}
-
-		row = append(row, recommendation.Name)
-		row = append(row, recommendation.Namespace)
-		row = append(row, recommendation.Spec.Type)
-
-		row = append(row, recommendation.Spec.TargetRef.Name)
-		row = append(row, recommendation.Namespace)
-		row = append(row, recommendation.Spec.TargetRef.Kind)
-
-		currentResource := ""
-		recommendResource := ""
-		switch recommendation.Spec.Type {
-		case "Resource":
-			var currentInfo analysisv1alpha1.PatchResource
-			if err := json.Unmarshal([]byte(recommendation.Status.RecommendationContent.CurrentInfo), &currentInfo); err != nil {
-				row = append(row, "")
-			} else {
-				for _, container := range currentInfo.Spec.Template.Spec.Containers {
-					currentResource += container.Name + "/" + container.Resources.Requests.Cpu().String() + "/" + container.Resources.Requests.Memory().String() + "\n"
-				}
-			}
+		t.AppendRows([]table.Row{
+			tableRow(recommendation, checker.Check(&recommendation), wide),
+		})
 
-			var recommendInfo analysisv1alpha1.PatchResource
-			if err := json.Unmarshal([]byte(recommendation.Status.RecommendationContent.RecommendedInfo), &recommendInfo); err != nil {
-				row = append(row, "")
-			} else {
-				for _, container := range recommendInfo.Spec.Template.Spec.Containers {
-					recommendResource += container.Name + "/" + container.Resources.Requests.Cpu().String() + "/" + container.Resources.Requests.Memory().String() + "\n"
-				}
-			}
-		case "Replicas":
-			var currentInfo analysisv1alpha1.PatchReplicas
-			if err := json.Unmarshal([]byte(recommendation.Status.RecommendationContent.CurrentInfo), &currentInfo); err != nil {
-				row = append(row, "")
-			} else {
-				currentResource += strconv.Itoa(int(*currentInfo.Spec.Replicas))
+		t.AppendSeparator()
+	}
+
+	t.Render()
+}
+
+func tableHeader(wide bool) table.Row {
+	header := table.Row{}
+	header = append(header, table.Row{"NAME", "NAMESPACE", "TYPE", "TARGET NAME", "TARGET NAMESPACE", "TARGET KIND", "CURRENT RESOURCE", "RECOMMEND RESOURCE", "ACTION", "FRESHNESS", "CREATED TIME", "UPDATED TIME"}...)
+	if wide {
+		header = append(header, table.Row{"RULE", "CONTAINER", "CURRENT CPU", "CURRENT MEMORY", "RECOMMEND CPU", "RECOMMEND MEMORY", "DRIFT", "DRIFTED VALUES"}...)
+	}
+
+	return header
+}
+
+func tableRow(recommendation analysisv1alpha1.Recommendation, health RecommendationHealth, wide bool) table.Row {
+	row := table.Row{}
+
+	row = append(row, recommendation.Name)
+	row = append(row, recommendation.Namespace)
+	row = append(row, recommendation.Spec.Type)
+
+	row = append(row, recommendation.Spec.TargetRef.Name)
+	row = append(row, recommendation.Namespace)
+	row = append(row, recommendation.Spec.TargetRef.Kind)
+
+	currentResource := ""
+	recommendResource := ""
+	var currentRequests, recommendRequests []utils.ContainerRequest
+	switch recommendation.Spec.Type {
+	case "Resource":
+		if requests, err := utils.DecodeResourceInfo(recommendation.Status.RecommendationContent.CurrentInfo); err == nil {
+			currentRequests = requests
+			for _, request := range requests {
+				currentResource += request.Name + "/" + request.Cpu.String() + "/" + request.Memory.String() + "\n"
 			}
+		}
 
-			var recommendInfo analysisv1alpha1.PatchReplicas
-			if err := json.Unmarshal([]byte(recommendation.Status.RecommendationContent.RecommendedInfo), &recommendInfo); err != nil {
-				row = append(row, "")
-			} else {
-				recommendResource += strconv.Itoa(int(*recommendInfo.Spec.Replicas))
+		if requests, err := utils.DecodeResourceInfo(recommendation.Status.RecommendationContent.RecommendedInfo); err == nil {
+			recommendRequests = requests
+			for _, request := range requests {
+				recommendResource += request.Name + "/" + request.Cpu.String() + "/" + request.Memory.String() + "\n"
 			}
-		default:
-			recommendResource = recommendation.Status.RecommendedInfo
-			currentResource = recommendation.Status.CurrentInfo
+		}
+	case "Replicas":
+		if replicas, err := utils.DecodeReplicasInfo(recommendation.Status.RecommendationContent.CurrentInfo); err == nil {
+			currentResource += strconv.Itoa(int(replicas))
 		}
 
-		row = append(row, currentResource)
-		row = append(row, recommendResource)
-		row = append(row, recommendation.Status.Action)
+		if replicas, err := utils.DecodeReplicasInfo(recommendation.Status.RecommendationContent.RecommendedInfo); err == nil {
+			recommendResource += strconv.Itoa(int(replicas))
+		}
+	case "IdleNode":
+		// the idle node recommendations carry no values, 'recommend nodes' shows the details
+		currentResource = "Node/" + recommendation.Spec.TargetRef.Name
+		recommendResource = recommendation.Status.Description
+	default:
+		recommendResource = recommendation.Status.RecommendedInfo
+		currentResource = recommendation.Status.CurrentInfo
+	}
 
-		row = append(row, recommendation.CreationTimestamp)
-		row = append(row, recommendation.Status.LastUpdateTime)
+	row = append(row, currentResource)
+	row = append(row, recommendResource)
+	action := recommendation.Status.Action
+	if len(health.Message) > 0 {
+		action = strings.TrimSpace(action + "\n" + health.Message)
+	}
+	row = append(row, action)
+	row = append(row, printFreshness(health))
 
-		t.AppendRows([]table.Row{
-			row,
-		})
+	row = append(row, recommendation.CreationTimestamp)
+	row = append(row, recommendation.Status.LastUpdateTime)
 
-		t.AppendSeparator()
+	if wide {
+		row = append(row, recommendation.Labels[RecommendationRuleNameLabel])
+		row = append(row, wideContainerColumns(currentRequests, recommendRequests)...)
+		row = append(row, health.Drift)
+		row = append(row, strings.Join(health.Drifts, "\n"))
 	}
 
-	t.Render()
+	return row
+}
+
+// wideContainerColumns lines up the current and recommended requests by container name,
+// one line per container in each cell.
+func wideContainerColumns(currentRequests, recommendRequests []utils.ContainerRequest) table.Row {
+	var containers, currentCpu, currentMemory, recommendCpu, recommendMemory []string
+
+	recommendMap := map[string]utils.ContainerRequest{}
+	for _, request := range recommendRequests {
+		recommendMap[request.Name] = request
+	}
+
+	for _, current := range currentRequests {
+		containers = append(containers, current.Name)
+		currentCpu = append(currentCpu, current.Cpu.String())
+		currentMemory = append(currentMemory, current.Memory.String())
+		if recommend, exist := recommendMap[current.Name]; exist {
+			recommendCpu = append(recommendCpu, recommend.Cpu.String())
+			recommendMemory = append(recommendMemory, recommend.Memory.String())
+		} else {
+			recommendCpu = append(recommendCpu, "-")
+			recommendMemory = append(recommendMemory, "-")
+		}
+	}
+
+	return table.Row{
+		strings.Join(containers, "\n"),
+		strings.Join(currentCpu, "\n"),
+		strings.Join(currentMemory, "\n"),
+		strings.Join(recommendCpu, "\n"),
+		strings.Join(recommendMemory, "\n"),
+	}
 }
 
 func (o *RecommendListOptions) AddFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&o.Type, "type", "", "", "List recommendation with specify recommend type[Resource, Replicas, IdleNode]")
 	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for recommendation")
-	cmd.Flags().StringVarP(&o.TargetKind, "targetKind", "", "", "List recommendation with specify recommendation target kind")
-	cmd.Flags().StringVarP(&o.TargetName, "targetName", "", "", "List recommendation with specify recommendation target name")
-	cmd.Flags().StringVarP(&o.RuleName, "ruleName", "", "", "List recommendation with specify recommendationRule name")
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "After listing the recommendations, watch for changes")
+	cmd.Flags().BoolVarP(&o.StaleOnly, "stale-only", "", false, "Only list the recommendations not updated for two run intervals of their rule")
+	cmd.Flags().BoolVarP(&o.DriftedOnly, "drifted-only", "", false, "Only list the recommendations whose target changed since they were computed")
+	o.FilterOptions.AddFlags(cmd)
 }
@@ -0,0 +1,548 @@
+package recommend
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+// manifestFile is a local yaml file holding one or more documents
+type manifestFile struct {
+	path      string
+	original  []byte
+	documents []*manifestDocument
+	changed   bool
+}
+
+// manifestDocument is a document of a manifest file with its original bytes,
+// only the changed documents are serialized again when the file is written.
+type manifestDocument struct {
+	// separator is the "---" line before the document, empty for the first document
+	separator string
+	original  string
+	// node is nil for the documents holding only comments
+	node      *kyaml.RNode
+	indent    int
+	seqIndent kyaml.SequenceIndentStyle
+	changed   bool
+}
+
+// ManifestWriter adopts recommendations into the workloads found in local yaml manifests
+// or kustomize overlays instead of the live cluster. The documents are edited in place
+// with kyaml so that the comments are kept, and the changed documents are written back
+// with their original indentation while the other documents are left byte for byte.
+type ManifestWriter struct {
+	Dir string
+
+	files []*manifestFile
+}
+
+// NewManifestWriter loads all the yaml files under the directory
+func NewManifestWriter(dir string) (*ManifestWriter, error) {
+	w := &ManifestWriter{Dir: dir}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		documents, err := splitDocuments(string(content))
+		if err != nil {
+			klog.Warningf("Skip the manifest %s which fails to parse, %v.", path, err)
+			return nil
+		}
+
+		w.files = append(w.files, &manifestFile{path: path, original: content, documents: documents})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests in %s, %v", dir, err)
+	}
+
+	return w, nil
+}
+
+// Find returns the documents describing the target, a document without namespace
+// matches any namespace because kustomize usually sets it in the overlay.
+func (w *ManifestWriter) Find(targetRef corev1.ObjectReference) []*kyaml.RNode {
+	var found []*kyaml.RNode
+	for _, file := range w.files {
+		for _, document := range file.documents {
+			if document.node != nil && matchTarget(document.node, targetRef) {
+				found = append(found, document.node)
+			}
+		}
+	}
+
+	return found
+}
+
+// Current returns the first document describing the target as an unstructured object,
+// it stands for the live object when checking the guardrails.
+func (w *ManifestWriter) Current(targetRef corev1.ObjectReference) (*unstructured.Unstructured, error) {
+	nodes := w.Find(targetRef)
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no manifest found for %s %s/%s in %s", targetRef.Kind, targetRef.Namespace, targetRef.Name, w.Dir)
+	}
+
+	object, err := nodes[0].Map()
+	if err != nil {
+		return nil, err
+	}
+
+	return &unstructured.Unstructured{Object: object}, nil
+}
+
+// Adopt writes the recommended info into the documents describing the target
+// and returns the files changed, or ErrAlreadyAdopted when the documents are up to date.
+func (w *ManifestWriter) Adopt(recommend *analysisv1alpha1.Recommendation, recommendedInfo string) ([]string, error) {
+	// the replicas are only written to the documents defining them, e.g. the overlay
+	// instead of the base, unless none of the documents defines them.
+	definesReplicas := false
+	for _, node := range w.Find(recommend.Spec.TargetRef) {
+		if replicas, err := node.Pipe(kyaml.Lookup("spec", "replicas")); err == nil && replicas != nil {
+			definesReplicas = true
+		}
+	}
+
+	var changed []string
+	matched := 0
+	// the containers or the replicas of the recommendation defined by any of the documents
+	defined := false
+	for _, file := range w.files {
+		fileChanged := false
+		for _, document := range file.documents {
+			node := document.node
+			if node == nil || !matchTarget(node, recommend.Spec.TargetRef) {
+				continue
+			}
+
+			var nodeChanged, nodeDefined bool
+			var err error
+			switch recommend.Spec.Type {
+			case "Resource":
+				nodeChanged, nodeDefined, err = setContainerRequests(node, recommendedInfo)
+			case "Replicas":
+				nodeChanged, err = setReplicas(node, recommendedInfo, !definesReplicas && matched == 0)
+				// the replicas are added to the first document when none defines them
+				nodeDefined = true
+			default:
+				return nil, fmt.Errorf("recommendation type %s is not supported for adoption", string(recommend.Spec.Type))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to update %s, %v", file.path, err)
+			}
+			document.changed = document.changed || nodeChanged
+			fileChanged = fileChanged || nodeChanged
+			defined = defined || nodeDefined
+			matched++
+		}
+
+		if fileChanged {
+			file.changed = true
+			changed = append(changed, file.path)
+		}
+	}
+
+	if matched > 0 && !defined {
+		return nil, fmt.Errorf("none of the recommended containers is defined by the manifests of %s %s/%s in %s", recommend.Spec.TargetRef.Kind, recommend.Spec.TargetRef.Namespace, recommend.Spec.TargetRef.Name, w.Dir)
+	}
+	if matched > 0 && len(changed) == 0 {
+		return nil, ErrAlreadyAdopted
+	}
+	if len(changed) == 0 {
+		return nil, fmt.Errorf("no manifest found for %s %s/%s in %s", recommend.Spec.TargetRef.Kind, recommend.Spec.TargetRef.Namespace, recommend.Spec.TargetRef.Name, w.Dir)
+	}
+
+	return changed, nil
+}
+
+// Changes returns the original and the updated content of the changed files
+func (w *ManifestWriter) Changes() (map[string][2][]byte, error) {
+	changes := map[string][2][]byte{}
+	for _, file := range w.files {
+		if !file.changed {
+			continue
+		}
+
+		var buf bytes.Buffer
+		for _, document := range file.documents {
+			buf.WriteString(document.separator)
+			if !document.changed {
+				buf.WriteString(document.original)
+				continue
+			}
+			content, err := document.encode()
+			if err != nil {
+				return nil, fmt.Errorf("failed to write %s, %v", file.path, err)
+			}
+			buf.Write(content)
+		}
+		if !bytes.Equal(buf.Bytes(), file.original) {
+			changes[file.path] = [2][]byte{file.original, buf.Bytes()}
+		}
+	}
+
+	return changes, nil
+}
+
+// splitDocuments splits the content at the "---" lines and parses every document
+func splitDocuments(content string) ([]*manifestDocument, error) {
+	documents := []*manifestDocument{{}}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if trimmed := strings.TrimRight(line, " \t\r\n"); trimmed == "---" || strings.HasPrefix(trimmed, "--- ") {
+			documents = append(documents, &manifestDocument{separator: line})
+			continue
+		}
+		documents[len(documents)-1].original += line
+	}
+
+	for _, document := range documents {
+		node, err := kyaml.Parse(document.original)
+		if errors.Is(err, io.EOF) {
+			// a document holding only comments
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if node.YNode().Kind != kyaml.MappingNode {
+			return nil, fmt.Errorf("the document is not a kubernetes object")
+		}
+		document.node = node
+		document.indent = documentIndent(document.original)
+		document.seqIndent = sequenceIndentStyle(document.original)
+	}
+
+	return documents, nil
+}
+
+// documentIndent returns the smallest indentation of the document, 2 when nothing is indented
+func documentIndent(content string) int {
+	indent := 0
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		if spaces := len(line) - len(trimmed); spaces > 0 && (indent == 0 || spaces < indent) {
+			indent = spaces
+		}
+	}
+	if indent == 0 {
+		return kyaml.DefaultIndent
+	}
+
+	return indent
+}
+
+// sequenceIndentStyle tells whether the sequences are indented under their keys, like kyaml.DeriveSeqIndentStyle
+// but for any indentation rather than 2 spaces only
+func sequenceIndentStyle(content string) kyaml.SequenceIndentStyle {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "- ") || i == 0 {
+			continue
+		}
+		key := lines[i-1]
+		if !strings.HasSuffix(strings.TrimSpace(key), ":") {
+			continue
+		}
+		if len(line)-len(trimmed) > len(key)-len(strings.TrimLeft(key, " ")) {
+			return kyaml.WideSequenceStyle
+		}
+		return kyaml.CompactSequenceStyle
+	}
+
+	return kyaml.CompactSequenceStyle
+}
+
+// encode serializes the changed document, the values changed in place are spliced into the original
+// text and the documents with new fields are encoded again with their original indentation.
+func (d *manifestDocument) encode() ([]byte, error) {
+	if content, ok := d.splice(); ok {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := kyaml.NewEncoder(&buf)
+	encoder.SetIndent(d.indent)
+	if d.seqIndent == kyaml.WideSequenceStyle {
+		encoder.DefaultSeqIndent()
+	}
+	if err := encoder.Encode(d.node.Document()); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Flush writes the changed files and returns their paths
+func (w *ManifestWriter) Flush() ([]string, error) {
+	changes, err := w.Changes()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path, change := range changes {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, change[1], info.Mode()); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func matchTarget(node *kyaml.RNode, targetRef corev1.ObjectReference) bool {
+	if node.GetKind() != targetRef.Kind || node.GetName() != targetRef.Name {
+		return false
+	}
+
+	if len(targetRef.APIVersion) > 0 && node.GetApiVersion() != targetRef.APIVersion {
+		return false
+	}
+
+	namespace := node.GetNamespace()
+	return len(namespace) == 0 || namespace == targetRef.Namespace
+}
+
+// setContainerRequests sets the recommended requests on the containers defined in the document. It returns
+// whether the document is changed, false when the containers already request the recommended values, and
+// whether the document defines any of the recommended containers.
+func setContainerRequests(node *kyaml.RNode, recommendedInfo string) (bool, bool, error) {
+	requests, err := utils.DecodeResourceInfo(recommendedInfo)
+	if err != nil {
+		return false, false, err
+	}
+
+	changed, defined := false, false
+	for _, request := range requests {
+		container, err := node.Pipe(kyaml.Lookup("spec", "template", "spec", "containers", "[name="+request.Name+"]"))
+		if err != nil {
+			return false, false, err
+		}
+		if container == nil {
+			continue
+		}
+		defined = true
+
+		for name, quantity := range map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceCPU:    request.Cpu,
+			corev1.ResourceMemory: request.Memory,
+		} {
+			if quantity.IsZero() {
+				continue
+			}
+			requestsNode, err := container.Pipe(kyaml.LookupCreate(kyaml.MappingNode, "resources", "requests"))
+			if err != nil {
+				return false, false, err
+			}
+			fieldChanged, err := setScalarField(requestsNode, string(name), quantity.String(), kyaml.NodeTagString, func(existing string) bool {
+				existingQuantity, err := resource.ParseQuantity(existing)
+				return err == nil && existingQuantity.Cmp(quantity) == 0
+			})
+			if err != nil {
+				return false, false, err
+			}
+			changed = changed || fieldChanged
+		}
+	}
+
+	return changed, defined, nil
+}
+
+// setReplicas sets the recommended replicas on the document when it defines the replicas,
+// or when force is set because none of the documents of the target defines them.
+// It returns false when the document already defines the recommended replicas.
+func setReplicas(node *kyaml.RNode, recommendedInfo string, force bool) (bool, error) {
+	replicas, err := utils.DecodeReplicasInfo(recommendedInfo)
+	if err != nil {
+		return false, err
+	}
+
+	current, err := node.Pipe(kyaml.Lookup("spec", "replicas"))
+	if err != nil {
+		return false, err
+	}
+	if current == nil && !force {
+		return false, nil
+	}
+
+	spec, err := node.Pipe(kyaml.LookupCreate(kyaml.MappingNode, "spec"))
+	if err != nil {
+		return false, err
+	}
+	if spec == nil {
+		return false, errors.New("spec not found")
+	}
+
+	return setScalarField(spec, "replicas", strconv.Itoa(int(replicas)), kyaml.NodeTagInt, func(existing string) bool {
+		existingReplicas, err := strconv.Atoi(existing)
+		return err == nil && existingReplicas == int(replicas)
+	})
+}
+
+// setScalarField updates the value of an existing field in place to keep its comments,
+// or adds the field when it doesn't exist. It returns false and leaves the field as it is
+// when the existing value is equal to the value.
+func setScalarField(node *kyaml.RNode, field, value, tag string, equal func(existing string) bool) (bool, error) {
+	style := kyaml.Style(0)
+	// quote the strings looking like numbers, e.g. cpu: "2"
+	if _, err := strconv.ParseFloat(value, 64); err == nil && tag == kyaml.NodeTagString {
+		style = kyaml.DoubleQuotedStyle
+	}
+
+	if existing := node.Field(field); existing != nil && existing.Value.YNode().Kind == kyaml.ScalarNode {
+		if equal(existing.Value.YNode().Value) {
+			return false, nil
+		}
+		existing.Value.YNode().Value = value
+		existing.Value.YNode().Tag = tag
+		existing.Value.YNode().Style = style
+		return true, nil
+	}
+
+	scalar := kyaml.NewScalarRNode(value)
+	scalar.YNode().Tag = tag
+	scalar.YNode().Style = style
+	return true, node.PipeE(kyaml.SetField(field, scalar))
+}
+
+// scalarEdit replaces the scalar at the line and column of the original document
+type scalarEdit struct {
+	line   int
+	column int
+	value  string
+}
+
+// splice writes the changed scalars into the original text, it returns false when
+// the structure of the document changed or a changed scalar spans several lines.
+func (d *manifestDocument) splice() ([]byte, bool) {
+	original, err := kyaml.Parse(d.original)
+	if err != nil {
+		return nil, false
+	}
+
+	var edits []scalarEdit
+	if !diffScalars(original.YNode(), d.node.YNode(), &edits) {
+		return nil, false
+	}
+
+	lines := strings.Split(d.original, "\n")
+	// edit the lines from the end so that the columns of the earlier edits are kept
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+	for _, edit := range edits {
+		if edit.line < 1 || edit.line > len(lines) {
+			return nil, false
+		}
+		line := lines[edit.line-1]
+		start := edit.column - 1
+		end := scalarEnd(line, start)
+		if start < 0 || end < start {
+			return nil, false
+		}
+		lines[edit.line-1] = line[:start] + edit.value + line[end:]
+	}
+
+	return []byte(strings.Join(lines, "\n")), true
+}
+
+// diffScalars collects the scalars changed from the original tree, the trees must have the same structure
+func diffScalars(original, changed *kyaml.Node, edits *[]scalarEdit) bool {
+	if original.Kind != changed.Kind || len(original.Content) != len(changed.Content) {
+		return false
+	}
+
+	if original.Kind == kyaml.ScalarNode {
+		if original.Value == changed.Value && original.Style == changed.Style {
+			return true
+		}
+		if original.Style&(kyaml.LiteralStyle|kyaml.FoldedStyle) != 0 || changed.Style&(kyaml.LiteralStyle|kyaml.FoldedStyle) != 0 {
+			return false
+		}
+		// keep the quotes of the original value
+		value := changed.Value
+		switch {
+		case changed.Style == kyaml.DoubleQuotedStyle || original.Style == kyaml.DoubleQuotedStyle:
+			value = strconv.Quote(value)
+		case original.Style == kyaml.SingleQuotedStyle:
+			value = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		}
+		*edits = append(*edits, scalarEdit{line: original.Line, column: original.Column, value: value})
+		return true
+	}
+
+	for i := range original.Content {
+		if !diffScalars(original.Content[i], changed.Content[i], edits) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scalarEnd returns the end of the scalar starting at start, the quotes included and the comment excluded
+func scalarEnd(line string, start int) int {
+	if start >= len(line) {
+		return -1
+	}
+
+	if quote := line[start]; quote == '"' || quote == '\'' {
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\\' && quote == '"' {
+				i++
+				continue
+			}
+			if line[i] == quote {
+				return i + 1
+			}
+		}
+		return -1
+	}
+
+	end := len(line)
+	if comment := strings.Index(line[start:], " #"); comment >= 0 {
+		end = start + comment
+	}
+
+	return start + len(strings.TrimRight(line[start:end], " \t\r"))
+}
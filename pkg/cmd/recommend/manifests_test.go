@@ -0,0 +1,339 @@
+package recommend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+)
+
+const webDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2 # scaled by hand
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        resources:
+          requests:
+            cpu: 500m # tuned
+            memory: 1Gi
+`
+
+func TestManifestWriterAdopt(t *testing.T) {
+	testCases := []struct {
+		name            string
+		manifest        string
+		recommendType   analysisv1alpha1.AnalysisType
+		recommendedInfo string
+		// the expected content of the manifest, empty when the manifest is not changed
+		expected    string
+		expectedErr error
+		expectFail  bool
+	}{
+		{
+			name:            "requests updated in place",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"250m","memory":"512Mi"}}}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2 # scaled by hand
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        resources:
+          requests:
+            cpu: 250m # tuned
+            memory: 512Mi
+`,
+		},
+		{
+			name:            "requests already up to date",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"0.5","memory":"1024Mi"}}}]}}}}`,
+			expectedErr:     ErrAlreadyAdopted,
+		},
+		{
+			name:            "missing recommended memory kept",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"250m"}}}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2 # scaled by hand
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        resources:
+          requests:
+            cpu: 250m # tuned
+            memory: 1Gi
+`,
+		},
+		{
+			name:            "zero recommended values skipped",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"0","memory":"0"}}}]}}}}`,
+			expectedErr:     ErrAlreadyAdopted,
+		},
+		{
+			name:            "unmatched container",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"other","resources":{"requests":{"cpu":"250m"}}}]}}}}`,
+			expectFail:      true,
+		},
+		{
+			name: "quoted requests kept quoted",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: "1"
+`,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"2"}}}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: "2"
+`,
+		},
+		{
+			name: "requests added to a container without resources",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: nginx
+`,
+			recommendType:   analysisv1alpha1.AnalysisTypeResource,
+			recommendedInfo: `{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"250m"}}}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: nginx
+          resources:
+            requests:
+              cpu: 250m
+`,
+		},
+		{
+			name:            "replicas updated in place",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeReplicas,
+			recommendedInfo: `{"spec":{"replicas":3}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3 # scaled by hand
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        resources:
+          requests:
+            cpu: 500m # tuned
+            memory: 1Gi
+`,
+		},
+		{
+			name:            "replicas already up to date",
+			manifest:        webDeployment,
+			recommendType:   analysisv1alpha1.AnalysisTypeReplicas,
+			recommendedInfo: `{"spec":{"replicas":2}}`,
+			expectedErr:     ErrAlreadyAdopted,
+		},
+		{
+			name: "replicas added when no document defines them",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+`,
+			recommendType:   analysisv1alpha1.AnalysisTypeReplicas,
+			recommendedInfo: `{"spec":{"replicas":3}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+  replicas: 3
+`,
+		},
+		{
+			name: "other documents left byte for byte",
+			manifest: `# the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+---
+apiVersion:   v1
+kind: Service
+metadata: {name: web}
+`,
+			recommendType:   analysisv1alpha1.AnalysisTypeReplicas,
+			recommendedInfo: `{"spec":{"replicas":4}}`,
+			expected: `# the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 4
+---
+apiVersion:   v1
+kind: Service
+metadata: {name: web}
+`,
+		},
+		{
+			name: "no manifest of the target",
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 2
+`,
+			recommendType:   analysisv1alpha1.AnalysisTypeReplicas,
+			recommendedInfo: `{"spec":{"replicas":3}}`,
+			expectFail:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "deployment.yaml")
+			if err := os.WriteFile(path, []byte(tc.manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			w, err := NewManifestWriter(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recommend := newRecommendation(tc.recommendType, tc.recommendedInfo)
+			recommend.Spec.TargetRef = corev1.ObjectReference{Kind: "Deployment", APIVersion: "apps/v1", Namespace: "default", Name: "web"}
+			files, err := w.Adopt(recommend, tc.recommendedInfo)
+			switch {
+			case tc.expectedErr != nil:
+				if !errors.Is(err, tc.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+				}
+			case tc.expectFail:
+				if err == nil || errors.Is(err, ErrAlreadyAdopted) {
+					t.Fatalf("expected the adoption to fail, got %v", err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case len(files) != 1 || files[0] != path:
+				t.Errorf("expected %s changed, got %v", path, files)
+			}
+
+			changes, err := w.Changes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.expected) == 0 {
+				if len(changes) > 0 {
+					t.Errorf("expected no change, got\n%s", changes[path][1])
+				}
+				return
+			}
+			if actual := string(changes[path][1]); actual != tc.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScalarEnd(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		start    int
+		expected int
+	}{
+		{name: "plain", line: "    cpu: 500m", start: 9, expected: 13},
+		{name: "trailing spaces", line: "    cpu: 500m  ", start: 9, expected: 13},
+		{name: "comment", line: "    cpu: 500m # tuned", start: 9, expected: 13},
+		{name: "double quoted", line: `    cpu: "1" # tuned`, start: 9, expected: 12},
+		{name: "escaped quote", line: `    name: "a\"b"`, start: 10, expected: 16},
+		{name: "single quoted", line: `    cpu: '1'`, start: 9, expected: 12},
+		{name: "unterminated quote", line: `    cpu: "1`, start: 9, expected: -1},
+		{name: "out of the line", line: "    cpu:", start: 9, expected: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := scalarEnd(tc.line, tc.start); actual != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	recommendNodesExample = `
+# view the idle nodes found by the recommendations in crane-system namespace
+%[1]s recommend nodes -n crane-system
+
+# view the idle nodes found by all the recommendations with the pods still running on them
+%[1]s recommend nodes -A -o wide
+
+# estimate the cost of the idle nodes with the prices per node type
+%[1]s recommend nodes -A --node-prices prices.yaml
+
+# cordon and drain an idle node
+%[1]s recommend adopt --name idlenodes-rule-idlenode-5dx8k -n crane-system
+`
+)
+
+// IdleNode is an idle node recommendation with the node it targets
+type IdleNode struct {
+	Recommendation analysisv1alpha1.Recommendation
+	// Node is nil when the node doesn't exist anymore
+	Node     *corev1.Node
+	NodeType string
+	// Pods are the pods evicted when draining the node
+	Pods        []corev1.Pod
+	MonthlyCost float64
+}
+
+type RecommendNodesOptions struct {
+	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
+	FilterOptions *RecommendFilterOptions
+
+	CpuPrice       float64
+	MemoryPrice    float64
+	NodePricesFile string
+	NodeTypeLabel  string
+
+	priceModel *utils.PriceModel
+}
+
+func NewRecommendNodesOptions() *RecommendNodesOptions {
+	return &RecommendNodesOptions{
+		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
+		FilterOptions: NewRecommendFilterOptions(),
+	}
+}
+
+func NewCmdRecommendNodes() *cobra.Command {
+	o := NewRecommendNodesOptions()
+
+	command := &cobra.Command{
+		Use:     "nodes",
+		Short:   "view the idle nodes recommended to remove",
+		Example: fmt.Sprintf(recommendNodesExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendNodesExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.PrintOptions.AddPrintFlags(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *RecommendNodesOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if o.FilterOptions.Type != analysisv1alpha1.IdleNodeRecommender {
+		return fmt.Errorf("the recommender type %s is not supported, only %s recommendations target nodes", o.FilterOptions.Type, analysisv1alpha1.IdleNodeRecommender)
+	}
+
+	if o.CpuPrice < 0 || o.MemoryPrice < 0 {
+		return errors.New("the prices should not be negative")
+	}
+
+	return nil
+}
+
+func (o *RecommendNodesOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(o.FilterOptions.Type) == 0 {
+		o.FilterOptions.Type = analysisv1alpha1.IdleNodeRecommender
+	}
+
+	o.priceModel = &utils.PriceModel{
+		Default: utils.ResourcePrice{
+			Cpu:    o.CpuPrice,
+			Memory: o.MemoryPrice,
+		},
+	}
+	if len(o.NodePricesFile) > 0 {
+		nodeTypes, err := utils.LoadNodeTypePrices(o.NodePricesFile)
+		if err != nil {
+			return err
+		}
+		o.priceModel.NodeTypes = nodeTypes
+	}
+
+	return nil
+}
+
+func (o *RecommendNodesOptions) Run() error {
+	recommendations, err := o.FilterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return err
+	}
+
+	if !o.PrintOptions.IsTable() {
+		return o.PrintOptions.PrintObj(&analysisv1alpha1.RecommendationList{Items: recommendations}, o.CommonOptions.Out)
+	}
+
+	var idleNodes []IdleNode
+	for _, recommendation := range recommendations {
+		if string(recommendation.Spec.Type) != analysisv1alpha1.IdleNodeRecommender {
+			continue
+		}
+
+		idleNode, err := DescribeIdleNode(o.CommonOptions, recommendation, o.priceModel, o.NodeTypeLabel)
+		if err != nil {
+			return err
+		}
+		idleNodes = append(idleNodes, *idleNode)
+	}
+
+	renderIdleNodes(idleNodes, o.CommonOptions.Out, o.PrintOptions.IsWide())
+
+	return nil
+}
+
+// DescribeIdleNode reads the node targeted by the recommendation and the pods still running on it,
+// the monthly cost of the node is estimated by its capacity.
+func DescribeIdleNode(commonOptions *options.CommonOptions, recommendation analysisv1alpha1.Recommendation, priceModel *utils.PriceModel, nodeTypeLabel string) (*IdleNode, error) {
+	idleNode := &IdleNode{Recommendation: recommendation}
+
+	node, err := commonOptions.KubeClient.CoreV1().Nodes().Get(context.TODO(), recommendation.Spec.TargetRef.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return idleNode, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	idleNode.Node = node
+	idleNode.NodeType = node.Labels[nodeTypeLabel]
+	idleNode.MonthlyCost = priceModel.PriceOf(idleNode.NodeType).MonthlyCost(*node.Status.Capacity.Cpu(), *node.Status.Capacity.Memory())
+
+	pods, err := ListNodePods(commonOptions, node.Name)
+	if err != nil {
+		return nil, err
+	}
+	idleNode.Pods = PodsToEvict(pods)
+
+	return idleNode, nil
+}
+
+// nodeStatus returns the status like kubectl get nodes
+func nodeStatus(node *corev1.Node) string {
+	status := "NotReady"
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			status = "Ready"
+		}
+	}
+	if node.Spec.Unschedulable {
+		status += ",SchedulingDisabled"
+	}
+
+	return status
+}
+
+func printGiB(quantity *resource.Quantity) string {
+	return fmt.Sprintf("%.2fGi", float64(quantity.Value())/(1<<30))
+}
+
+func renderIdleNodes(idleNodes []IdleNode, out io.Writer, wide bool) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.Style().Format.Footer = text.FormatDefault
+
+	header := table.Row{"NAME", "NAMESPACE", "NODE", "NODE TYPE", "STATUS", "CPU CAPACITY", "CPU ALLOCATABLE", "MEMORY CAPACITY", "MEMORY ALLOCATABLE", "PODS", "MONTHLY COST"}
+	if wide {
+		header = append(header, "EVICTED PODS")
+	}
+	t.AppendHeader(header)
+
+	totalCost := 0.0
+	for _, idleNode := range idleNodes {
+		row := table.Row{idleNode.Recommendation.Name, idleNode.Recommendation.Namespace, idleNode.Recommendation.Spec.TargetRef.Name}
+		if idleNode.Node == nil {
+			row = append(row, "-", "NotFound", "-", "-", "-", "-", "-", "-")
+		} else {
+			node := idleNode.Node
+			row = append(row,
+				idleNode.NodeType,
+				nodeStatus(node),
+				node.Status.Capacity.Cpu().String(),
+				node.Status.Allocatable.Cpu().String(),
+				printGiB(node.Status.Capacity.Memory()),
+				printGiB(node.Status.Allocatable.Memory()),
+				len(idleNode.Pods),
+				fmt.Sprintf("%.2f", idleNode.MonthlyCost),
+			)
+			totalCost += idleNode.MonthlyCost
+		}
+		if wide {
+			var pods []string
+			for _, pod := range idleNode.Pods {
+				pods = append(pods, pod.Namespace+"/"+pod.Name)
+			}
+			row = append(row, strings.Join(pods, "\n"))
+		}
+		t.AppendRow(row)
+		t.AppendSeparator()
+	}
+
+	t.AppendFooter(table.Row{"Total", len(idleNodes), "", "", "", "", "", "", "", "", fmt.Sprintf("%.2f", totalCost)})
+	t.Render()
+}
+
+func (o *RecommendNodesOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64VarP(&o.CpuPrice, "cpu-price", "", utils.DefaultCpuPrice, "The price of one vCPU per hour")
+	cmd.Flags().Float64VarP(&o.MemoryPrice, "memory-price", "", utils.DefaultMemoryPrice, "The price of one GiB of memory per hour")
+	cmd.Flags().StringVarP(&o.NodePricesFile, "node-prices", "", "", "The yaml file with the prices of vCPU and memory per node type, the other nodes use the default prices")
+	cmd.Flags().StringVarP(&o.NodeTypeLabel, "node-type-label", "", utils.DefaultNodeTypeLabel, "The label of nodes holding the node type")
+	o.FilterOptions.AddFlags(cmd)
+}
@@ -0,0 +1,472 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	recommendRollbackExample = `
+# restore the values overwritten when the specified recommendation was adopted
+%[1]s recommend rollback --name workloads-rule-resource-ntzns -n kube-system
+
+# pre-commit
+%[1]s recommend rollback --name workloads-rule-resource-ntzns -n kube-system --dry-run
+
+# restore the HPA or EHPA updated with a Replicas recommendation, or delete the EHPA created with it
+%[1]s recommend rollback --name workloads-rule-replicas-4kp2s -n default
+`
+)
+
+const (
+	// PreviousValueAnnotationPrefix is the prefix of the annotation recorded on the target
+	// when a recommendation is adopted, the suffix is the lower case recommendation type.
+	PreviousValueAnnotationPrefix = "analysis.crane.io/previous-"
+)
+
+// ErrAlreadyAdopted is returned when the target already has the recommended values
+var ErrAlreadyAdopted = errors.New("the target already has the recommended values")
+
+// AdoptionRecord is the value of the previous value annotation, PreviousInfo is
+// a strategic merge patch restoring the fields overwritten by the recommendation,
+// or a merge patch when it is recorded on an autoscaler.
+type AdoptionRecord struct {
+	Recommendation string      `json:"recommendation"`
+	AdoptedTime    metav1.Time `json:"adoptedTime"`
+	PreviousInfo   string      `json:"previousInfo"`
+	// Created is set on the EHPA created by the adoption, the rollback deletes it
+	Created bool `json:"created,omitempty"`
+}
+
+type RecommendRollbackOptions struct {
+	CommonOptions *options.CommonOptions
+
+	DryRun bool
+	Name   string
+}
+
+func NewRecommendRollbackOptions() *RecommendRollbackOptions {
+	return &RecommendRollbackOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdRecommendRollback() *cobra.Command {
+	o := NewRecommendRollbackOptions()
+
+	command := &cobra.Command{
+		Use:     "rollback",
+		Short:   "Rollback an adopted recommend",
+		Example: fmt.Sprintf(recommendRollbackExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendRollbackExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.AddFlags(command)
+	o.CommonOptions.AddCommonFlag(command)
+
+	return command
+}
+
+func (o *RecommendRollbackOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommend name")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+		return errors.New("please specify the recommend namespace")
+	}
+
+	return nil
+}
+
+func (o *RecommendRollbackOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *RecommendRollbackOptions) Run() error {
+	recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+	}
+
+	targetRef := recommend.Spec.TargetRef
+	gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		return fmt.Errorf("failed to get the resource of %s %s, %v", targetRef.APIVersion, targetRef.Kind, err)
+	}
+
+	live, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the target %s/%s, %v", targetRef.Namespace, targetRef.Name, err)
+	}
+
+	annotation := previousValueAnnotation(recommend.Spec.Type)
+	value, exist := live.GetAnnotations()[annotation]
+	patchType := types.StrategicMergePatchType
+	// the Replicas recommendation adopted into the autoscaler of the target is recorded on the autoscaler
+	if !exist && string(recommend.Spec.Type) == analysisv1alpha1.ReplicasRecommender {
+		autoscaler, err := FindAutoscaler(o.CommonOptions, targetRef)
+		if err != nil {
+			return fmt.Errorf("failed to find the autoscaler of %s/%s, %v", targetRef.Namespace, targetRef.Name, err)
+		}
+		if autoscaler != nil {
+			autoscalerGVR := autoscaler.GroupVersionResource()
+			autoscalerLive, err := o.CommonOptions.DynamicClient.Resource(autoscalerGVR).Namespace(autoscaler.Namespace).Get(context.TODO(), autoscaler.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get the %s, %v", autoscaler, err)
+			}
+			if value, exist = autoscalerLive.GetAnnotations()[annotation]; exist {
+				gvr, live, patchType = &autoscalerGVR, autoscalerLive, types.MergePatchType
+				targetRef = corev1.ObjectReference{Kind: autoscaler.Kind, Namespace: autoscaler.Namespace, Name: autoscaler.Name}
+			}
+		}
+	}
+	if !exist {
+		return fmt.Errorf("no adoption of %s recommendation is recorded on %s/%s", recommend.Spec.Type, targetRef.Namespace, targetRef.Name)
+	}
+
+	var record AdoptionRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return fmt.Errorf("the annotation %s on %s/%s is invalid, %v", annotation, targetRef.Namespace, targetRef.Name, err)
+	}
+
+	if record.Recommendation != recommend.Name {
+		klog.Warningf("the recorded values were saved when adopting the recommendation %s, restoring the values from before it", record.Recommendation)
+	}
+
+	// the EHPA created by the adoption is deleted
+	if record.Created {
+		deleteOptions := metav1.DeleteOptions{}
+		if o.DryRun {
+			deleteOptions.DryRun = []string{"All"}
+		}
+		if err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Delete(context.TODO(), targetRef.Name, deleteOptions); err != nil {
+			return fmt.Errorf("rollback the recommend failed because %v", err)
+		}
+		klog.Infof("success to rollback the recommendation %s by deleting the %s %s/%s created at %s", o.Name, targetRef.Kind, targetRef.Namespace, targetRef.Name, record.AdoptedTime)
+		return nil
+	}
+
+	// restore the previous values and remove the record in the same patch
+	patch, err := withAnnotation(record.PreviousInfo, annotation, nil)
+	if err != nil {
+		return err
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if o.DryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+
+	patched, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Patch(context.TODO(), targetRef.Name, patchType, patch, patchOptions)
+	if err != nil {
+		return fmt.Errorf("rollback the recommend failed because %v", err)
+	}
+
+	// when dry-run set, print the object
+	if o.DryRun {
+		printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		if err = printer.PrintObj(patched, o.CommonOptions.Out); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	klog.Infof(fmt.Sprintf("success to rollback the recommendation %s adopted at %s", o.Name, record.AdoptedTime))
+	return nil
+}
+
+func previousValueAnnotation(recommendType analysisv1alpha1.AnalysisType) string {
+	return PreviousValueAnnotationPrefix + strings.ToLower(string(recommendType))
+}
+
+// buildAdoptPatch returns the RecommendedInfo patch which also records the values
+// of the live target it overwrites, so that the adoption can be rolled back.
+// The record of an earlier adoption is kept, so that the rollback restores the values
+// from before any adoption rather than the values of the earlier recommendation, and
+// the values of the fields the earlier adoption didn't overwrite are merged into it.
+func buildAdoptPatch(live *unstructured.Unstructured, recommend *analysisv1alpha1.Recommendation) ([]byte, error) {
+	patched, err := patchLocally(live, []byte(recommend.Status.RecommendedInfo))
+	if err != nil {
+		return nil, err
+	}
+	if equality.Semantic.DeepEqual(live.Object["spec"], patched.Object["spec"]) {
+		return nil, ErrAlreadyAdopted
+	}
+
+	previousInfo, err := buildPreviousInfo(live, recommend)
+	if err != nil {
+		return nil, err
+	}
+
+	annotation := previousValueAnnotation(recommend.Spec.Type)
+	record, err := mergeAdoptionRecord(live.GetAnnotations()[annotation], AdoptionRecord{
+		Recommendation: recommend.Name,
+		AdoptedTime:    metav1.Now(),
+		PreviousInfo:   previousInfo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("the annotation %s on %s/%s is invalid, %v", annotation, live.GetNamespace(), live.GetName(), err)
+	}
+	if len(record) == 0 {
+		return []byte(recommend.Status.RecommendedInfo), nil
+	}
+
+	return withAnnotation(recommend.Status.RecommendedInfo, annotation, record)
+}
+
+// mergeAdoptionRecord returns the annotation value recording the adoption, or an empty value when
+// the recorded value is kept as it is. The recorded adoption is kept with the previous values of
+// the fields it doesn't restore merged from the new adoption.
+func mergeAdoptionRecord(recorded string, adoption AdoptionRecord) (string, error) {
+	if len(recorded) == 0 {
+		record, err := json.Marshal(adoption)
+		return string(record), err
+	}
+
+	var record AdoptionRecord
+	if err := json.Unmarshal([]byte(recorded), &record); err != nil {
+		return "", err
+	}
+	// the EHPA created by the adoption is deleted by the rollback, there is nothing to restore
+	if record.Created {
+		return "", nil
+	}
+
+	var recordedInfo, previousInfo map[string]interface{}
+	if err := json.Unmarshal([]byte(record.PreviousInfo), &recordedInfo); err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal([]byte(adoption.PreviousInfo), &previousInfo); err != nil {
+		return "", err
+	}
+	if !mergeMissingFields(recordedInfo, previousInfo) {
+		return "", nil
+	}
+
+	mergedInfo, err := json.Marshal(recordedInfo)
+	if err != nil {
+		return "", err
+	}
+	record.PreviousInfo = string(mergedInfo)
+	merged, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	return string(merged), nil
+}
+
+// mergeMissingFields adds the fields of the previous values missing in the recorded ones, the recorded
+// values win since they are from before any adoption. The list items, i.e. the containers, are matched
+// by name. It returns true when any field is added.
+func mergeMissingFields(recorded, previous map[string]interface{}) bool {
+	merged := false
+	for field, value := range previous {
+		recordedValue, exist := recorded[field]
+		if !exist {
+			recorded[field] = value
+			merged = true
+			continue
+		}
+
+		switch previousValue := value.(type) {
+		case map[string]interface{}:
+			if recordedMap, ok := recordedValue.(map[string]interface{}); ok {
+				merged = mergeMissingFields(recordedMap, previousValue) || merged
+			}
+		case []interface{}:
+			if recordedList, ok := recordedValue.([]interface{}); ok {
+				mergedList, listMerged := mergeMissingItems(recordedList, previousValue)
+				recorded[field] = mergedList
+				merged = listMerged || merged
+			}
+		}
+	}
+
+	return merged
+}
+
+// mergeMissingItems merges the items of the lists with the same name and appends the missing ones,
+// the lists of items without name, e.g. the metrics of an autoscaler, are restored as a whole.
+func mergeMissingItems(recorded, previous []interface{}) ([]interface{}, bool) {
+	merged := false
+	for _, item := range previous {
+		previousItem, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, named := previousItem["name"]; !named {
+			continue
+		}
+
+		found := false
+		for _, recordedItem := range recorded {
+			recordedItemMap, ok := recordedItem.(map[string]interface{})
+			if ok && recordedItemMap["name"] == previousItem["name"] {
+				merged = mergeMissingFields(recordedItemMap, previousItem) || merged
+				found = true
+				break
+			}
+		}
+		if !found {
+			recorded = append(recorded, previousItem)
+			merged = true
+		}
+	}
+
+	return recorded, merged
+}
+
+// buildPreviousInfo builds a patch restoring the fields of the live target that the RecommendedInfo sets,
+// the resources missing on the live target are set to null so that the patch removes them.
+func buildPreviousInfo(live *unstructured.Unstructured, recommend *analysisv1alpha1.Recommendation) (string, error) {
+	var patch map[string]interface{}
+	switch recommend.Spec.Type {
+	case "Resource":
+		var recommendInfo analysisv1alpha1.PatchResource
+		if err := json.Unmarshal([]byte(recommend.Status.RecommendedInfo), &recommendInfo); err != nil {
+			return "", err
+		}
+
+		liveContainers, _, err := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+		if err != nil {
+			return "", err
+		}
+
+		var containers []interface{}
+		for _, container := range recommendInfo.Spec.Template.Spec.Containers {
+			var liveResources map[string]interface{}
+			for _, liveContainer := range liveContainers {
+				liveContainerMap, ok := liveContainer.(map[string]interface{})
+				if ok && liveContainerMap["name"] == container.Name {
+					liveResources, _, _ = unstructured.NestedMap(liveContainerMap, "resources")
+				}
+			}
+
+			resources := map[string]interface{}{}
+			for field, resourceList := range map[string]map[string]string{
+				"requests": quantityStrings(container.Resources.Requests),
+				"limits":   quantityStrings(container.Resources.Limits),
+			} {
+				if len(resourceList) == 0 {
+					continue
+				}
+				liveList, _, _ := unstructured.NestedStringMap(liveResources, field)
+				values := map[string]interface{}{}
+				for name := range resourceList {
+					if value, exist := liveList[name]; exist {
+						values[name] = value
+					} else {
+						values[name] = nil
+					}
+				}
+				resources[field] = values
+			}
+
+			containers = append(containers, map[string]interface{}{
+				"name":      container.Name,
+				"resources": resources,
+			})
+		}
+
+		patch = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": containers,
+					},
+				},
+			},
+		}
+	case "Replicas":
+		var replicas interface{}
+		if liveReplicas, found, err := unstructured.NestedInt64(live.Object, "spec", "replicas"); err == nil && found {
+			replicas = liveReplicas
+		}
+
+		patch = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+			},
+		}
+	default:
+		return "", fmt.Errorf("recommendation type %s is not supported for rollback", string(recommend.Spec.Type))
+	}
+
+	previousInfo, err := json.Marshal(patch)
+	if err != nil {
+		return "", err
+	}
+
+	return string(previousInfo), nil
+}
+
+// withAnnotation adds the annotation to the patch, a nil value removes the annotation
+func withAnnotation(patch string, annotation string, value interface{}) ([]byte, error) {
+	patchMap := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(patch), &patchMap); err != nil {
+		return nil, err
+	}
+
+	if err := unstructured.SetNestedField(patchMap, value, "metadata", "annotations", annotation); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(patchMap)
+}
+
+func quantityStrings(resourceList corev1.ResourceList) map[string]string {
+	values := map[string]string{}
+	for name, quantity := range resourceList {
+		values[string(name)] = quantity.String()
+	}
+
+	return values
+}
+
+func (o *RecommendRollbackOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for recommend")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+}
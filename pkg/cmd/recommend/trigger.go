@@ -2,15 +2,30 @@ package recommend
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
-	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
 )
 
 var (
@@ -20,19 +35,33 @@ var (
 
 # pre-commit
 %[1]s recommend trigger --name workloads-rule-resource-kjdfh -n kube-system --dry-run
+
+# trigger the specified recommendation and wait until it is recomputed
+%[1]s recommend trigger --name workloads-rule-resource-kjdfh -n kube-system --wait --timeout 10m
+
+# trigger all the Resource recommendations of Deployments in all namespaces
+%[1]s recommend trigger -A --type Resource --targetKind Deployment
 `
 )
 
+// DefaultTriggerConcurrency is the default number of recommendations triggered in parallel
+const DefaultTriggerConcurrency = 10
+
 type RecommendTriggerOptions struct {
 	CommonOptions *options.CommonOptions
+	FilterOptions *RecommendFilterOptions
 
-	DryRun bool
-	Name   string
+	DryRun      bool
+	Name        string
+	Wait        bool
+	Timeout     time.Duration
+	Concurrency int
 }
 
 func NewRecommendTriggerOptions() *RecommendTriggerOptions {
 	return &RecommendTriggerOptions{
 		CommonOptions: options.NewCommonOptions(),
+		FilterOptions: NewRecommendFilterOptions(),
 	}
 }
 
@@ -71,14 +100,34 @@ func (o *RecommendTriggerOptions) Validate() error {
 		return err
 	}
 
-	if len(o.Name) == 0 {
-		return errors.New("please specify the recommend name")
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
 	}
 
-	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 {
+	if len(o.Name) == 0 && o.FilterOptions.IsEmpty() {
+		return errors.New("please specify the recommend name or the selectors of recommendations")
+	}
+
+	if len(o.Name) > 0 && (!o.FilterOptions.IsEmpty() || o.FilterOptions.AllNamespaces) {
+		return errors.New("the recommend name can't be used with the selectors of recommendations")
+	}
+
+	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 && !o.FilterOptions.AllNamespaces {
 		return errors.New("please specify the recommend namespace")
 	}
 
+	if o.Concurrency <= 0 {
+		return errors.New("please specify a positive concurrency")
+	}
+
+	if o.Wait && o.DryRun {
+		return errors.New("--wait can't be used with --dry-run")
+	}
+
+	if o.Timeout <= 0 {
+		return errors.New("please specify a positive timeout")
+	}
+
 	return nil
 }
 
@@ -91,43 +140,206 @@ func (o *RecommendTriggerOptions) Complete(cmd *cobra.Command, args []string) er
 }
 
 func (o *RecommendTriggerOptions) Run() error {
-	recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
-	if err != nil {
-		return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+	var recommendations []analysisv1alpha1.Recommendation
+	if len(o.Name) > 0 {
+		recommend, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Get(context.TODO(), o.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.New("the recommend doesn't exist, please specify a existed recommend name with --name")
+		}
+		recommendations = append(recommendations, *recommend)
+	} else {
+		var err error
+		recommendations, err = o.FilterOptions.ListRecommendations(o.CommonOptions)
+		if err != nil {
+			return err
+		}
+		if len(recommendations) == 0 {
+			klog.Infof("no recommendation matches the selectors")
+			return nil
+		}
 	}
 
-	// Modify the run number annotation for the recommendation that should be triggered.
-	// The run number should be lower than the runNumber in the recommendationRule.
-	// Just set the run number annotation to zero
-	if recommend.Annotations == nil {
-		recommend.Annotations = make(map[string]string, 0)
-	}
-	recommend.Annotations[RunNumberAnnotation] = "0"
-	updateOptions := metav1.UpdateOptions{}
-	if o.DryRun {
-		updateOptions.DryRun = []string{"All"}
-	}
-	if recommend, err = o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(*o.CommonOptions.ConfigFlags.Namespace).Update(context.TODO(), recommend, updateOptions); err != nil {
-		return fmt.Errorf("failed to trigger the recommendation %s, %v", recommend.Name, err)
-	}
+	results := TriggerRecommendations(o.CommonOptions, recommendations, o.DryRun, o.Concurrency)
 
 	// when dry-run set, print the object
-	if o.DryRun {
+	if o.DryRun && len(o.Name) > 0 {
+		if results[0].Err != nil {
+			return results[0].Err
+		}
+
+		recommend := results[0].Recommendation
 		recommend.Kind = "Recommendation"
 		recommend.APIVersion = "analysis.crane.io/v1alpha1"
 		printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
-		if err = printer.PrintObj(recommend, o.CommonOptions.Out); err != nil {
+		if err := printer.PrintObj(recommend, o.CommonOptions.Out); err != nil {
 			return err
 		}
 
 		return nil
 	}
 
-	klog.Infof(fmt.Sprintf("success to trigger the recommendation %s", o.Name))
+	if err := SummarizeTriggerResults(results, o.DryRun); err != nil {
+		return err
+	}
+
+	if o.Wait {
+		ctx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+		defer cancel()
+
+		klog.Infof("waiting for %d recommendations to be recomputed", len(results))
+		recomputed := make([]analysisv1alpha1.Recommendation, len(results))
+		errs := make([]error, len(results))
+		// every piece has to run to report its own timeout, so the workers are not stopped by ctx
+		workqueue.ParallelizeUntil(context.TODO(), o.Concurrency, len(results), func(i int) {
+			recommend, err := WaitForRecommendation(ctx, o.CommonOptions, results[i].Recommendation.Namespace, results[i].Recommendation.Name, results[i].TriggerTime)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			recomputed[i] = *recommend
+		})
+		if err := utilerrors.NewAggregate(errs); err != nil {
+			return err
+		}
+
+		RenderTable(recomputed, NewHealthChecker(o.CommonOptions, false), o.CommonOptions.Out, false)
+	}
+
 	return nil
 }
 
+// TriggerResult is the result of triggering a recommendation, Recommendation is the updated
+// object and TriggerTime is the time just before the update.
+type TriggerResult struct {
+	Recommendation *analysisv1alpha1.Recommendation
+	TriggerTime    time.Time
+	Err            error
+}
+
+// TriggerRecommendations triggers the recommendations with at most concurrency updates in flight,
+// the progress is logged as the updates complete and the results keep the order of the recommendations.
+func TriggerRecommendations(commonOptions *options.CommonOptions, recommendations []analysisv1alpha1.Recommendation, dryRun bool, concurrency int) []TriggerResult {
+	results := make([]TriggerResult, len(recommendations))
+
+	var lock sync.Mutex
+	done := 0
+	workqueue.ParallelizeUntil(context.TODO(), concurrency, len(recommendations), func(i int) {
+		recommend := &recommendations[i]
+		results[i].TriggerTime = time.Now()
+		results[i].Recommendation, results[i].Err = triggerRecommendation(commonOptions, recommend, dryRun)
+
+		lock.Lock()
+		defer lock.Unlock()
+		done++
+		if results[i].Err != nil {
+			klog.Errorf("[%d/%d] failed to trigger the recommendation %s/%s, %v", done, len(recommendations), recommend.Namespace, recommend.Name, results[i].Err)
+			results[i].Recommendation = recommend
+		} else if len(recommendations) > 1 {
+			klog.Infof("[%d/%d] triggered the recommendation %s/%s", done, len(recommendations), recommend.Namespace, recommend.Name)
+		}
+	})
+
+	return results
+}
+
+// SummarizeTriggerResults logs how many recommendations are triggered, and returns an error if any failed
+func SummarizeTriggerResults(results []TriggerResult, dryRun bool) error {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	suffix := ""
+	if dryRun {
+		suffix = " (dry run)"
+	}
+	if len(results) == 1 && failed == 0 {
+		klog.Infof(fmt.Sprintf("success to trigger the recommendation %s%s", results[0].Recommendation.Name, suffix))
+		return nil
+	}
+
+	klog.Infof("%d recommendations triggered, %d failed%s", len(results)-failed, failed, suffix)
+	if failed > 0 {
+		return fmt.Errorf("failed to trigger %d recommendations", failed)
+	}
+
+	return nil
+}
+
+// triggerRecommendation resets the run number annotation of the recommendation. The run number should
+// be lower than the runNumber in the recommendationRule, so just set the run number annotation to zero.
+func triggerRecommendation(commonOptions *options.CommonOptions, recommend *analysisv1alpha1.Recommendation, dryRun bool) (*analysisv1alpha1.Recommendation, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				RunNumberAnnotation: "0",
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if dryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+
+	return commonOptions.CraneClient.AnalysisV1alpha1().Recommendations(recommend.Namespace).Patch(context.TODO(), recommend.Name, types.MergePatchType, patch, patchOptions)
+}
+
+// WaitForRecommendation watches the recommendation until it is recomputed after the trigger time, that is
+// its LastUpdateTime moves past the trigger time or the controller sets the run number annotation again.
+func WaitForRecommendation(ctx context.Context, commonOptions *options.CommonOptions, namespace, name string, triggerTime time.Time) (*analysisv1alpha1.Recommendation, error) {
+	client := commonOptions.CraneClient.AnalysisV1alpha1().Recommendations(namespace)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return client.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return client.Watch(ctx, options)
+		},
+	}
+
+	// LastUpdateTime is stored in seconds
+	since := triggerTime.Truncate(time.Second)
+	event, err := watchtools.UntilWithSync(ctx, lw, &analysisv1alpha1.Recommendation{}, nil, func(event watch.Event) (bool, error) {
+		switch event.Type {
+		case watch.Deleted:
+			return false, fmt.Errorf("the recommendation %s/%s is deleted", namespace, name)
+		case watch.Added, watch.Modified:
+			recommend, ok := event.Object.(*analysisv1alpha1.Recommendation)
+			if !ok {
+				return false, nil
+			}
+			if runNumber, exist := recommend.Annotations[RunNumberAnnotation]; exist && runNumber != "0" {
+				return true, nil
+			}
+			return recommend.Status.LastUpdateTime != nil && recommend.Status.LastUpdateTime.After(since), nil
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out waiting for the recommendation %s/%s to be recomputed", namespace, name)
+		}
+		return nil, err
+	}
+
+	return event.Object.(*analysisv1alpha1.Recommendation), nil
+}
+
 func (o *RecommendTriggerOptions) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify the name for recommend")
 	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+	cmd.Flags().BoolVarP(&o.Wait, "wait", "", false, "Wait until the recommendation is recomputed and print the result")
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 10*time.Minute, "The length of time to wait for the recommendation to be recomputed")
+	cmd.Flags().IntVarP(&o.Concurrency, "concurrency", "", DefaultTriggerConcurrency, "The number of recommendations triggered in parallel")
+	o.FilterOptions.AddFlags(cmd)
 }
@@ -5,12 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
-	"strings"
 
 	"github.com/gocrane/api/analysis/v1alpha1"
 
@@ -30,6 +31,21 @@ var (
 
 # create a simple recommendation rule for all namespace with Any and Resource\Replicas recommender
 %[1]s rr create --namespace Any --recommender Resource,Replicas --target '[{"kind": "Deployment", "apiVersion": "apps/v1"}]' --run-interval 4h
+
+# create a recommendation rule for the Deployments labelled app=web without writing json
+%[1]s rr create --name web-rule --kind Deployment --api-version apps/v1 --label-selector app=web --run-interval 4h
+
+# create a recommendation rule for the StatefulSets whose name starts with redis, only the existing ones are covered
+%[1]s rr create --name redis-rule --kind StatefulSet --api-version apps/v1 --name-pattern 'redis*' --namespace default --run-interval 4h
+
+# create a recommendation rule with a higher cpu percentile and oom protection of the Resource recommender
+%[1]s rr create --name workloads-rule --target '[{"kind": "Deployment", "apiVersion": "apps/v1"}]' --run-interval 4h --recommender-config Resource.cpu-request-percentile=0.95 --recommender-config Resource.oom-protection=true
+
+# create a recommendation rule with the recommender configs in a file
+%[1]s rr create --name workloads-rule --recommender Resource,Replicas --target '[{"kind": "Deployment", "apiVersion": "apps/v1"}]' --run-interval 4h --recommender-config-file recommenders.yaml
+
+# create or update the recommendation rules in a file with server-side apply
+%[1]s rr create -f rule.yaml
 `
 )
 
@@ -42,7 +58,24 @@ type RecommendationRuleCreateOptions struct {
 	DryRun      bool
 	Name        string
 
+	Filename       string
+	ForceConflicts bool
+
+	Kind          string
+	APIVersion    string
+	LabelSelector string
+	NamePattern   string
+
+	RecommenderConfigs    []string
+	RecommenderConfigFile string
+
 	ResourceSelectors []v1alpha1.ResourceSelector
+
+	// the configs of each recommender, the options in the flags override the ones in the config file
+	recommenderConfigs map[string]map[string]string
+
+	// the recommendation rules loaded from the file
+	recommendationRules []*v1alpha1.RecommendationRule
 }
 
 func NewRecommendationRuleCreateOptions() *RecommendationRuleCreateOptions {
@@ -85,33 +118,60 @@ func (o *RecommendationRuleCreateOptions) Validate() error {
 		return err
 	}
 
-	err := json.Unmarshal([]byte(o.Target), &o.ResourceSelectors)
-	if err != nil {
-		return errors.New("please check the recommender target is valid")
-	}
+	friendlyTarget := len(o.Kind) > 0 || len(o.APIVersion) > 0 || len(o.LabelSelector) > 0 || len(o.NamePattern) > 0
 
-	recommenders := strings.Split(o.Recommender, ",")
-	for _, recommender := range recommenders {
-		typeExist := false
-		for _, recommenderType := range v1alpha1.AllRecommenderType {
-			if recommenderType == recommender {
-				typeExist = true
-			}
+	if len(o.Filename) > 0 {
+		if len(o.Target) > 0 || friendlyTarget || len(o.Name) > 0 || len(o.RecommenderConfigs) > 0 || len(o.RecommenderConfigFile) > 0 {
+			return errors.New("--filename can't be used with --name, --target, --kind, --api-version, --label-selector, --name-pattern or the recommender configs")
+		}
+
+		if len(o.recommendationRules) == 0 {
+			return fmt.Errorf("please specify a file containing recommendation rules, no recommendation rule found in %s", o.Filename)
 		}
-		if !typeExist {
-			return fmt.Errorf("the recommender type not supported %s", recommender)
+		for _, recommendationRule := range o.recommendationRules {
+			if err := ValidateRecommendationRule(recommendationRule); err != nil {
+				return fmt.Errorf("invalid recommendation rule %s in %s, %v", recommendationRule.Name, o.Filename, err)
+			}
 		}
+
+		return nil
+	}
+
+	if len(o.Target) > 0 && friendlyTarget {
+		return errors.New("--target can't be used with --kind, --api-version, --label-selector or --name-pattern")
 	}
 
-	if len(o.RunInterval) == 0 {
-		return errors.New("please specify the runInterval with --runInterval")
+	if friendlyTarget {
+		if len(o.Kind) == 0 {
+			return errors.New("please specify the kind of the recommender target with --kind")
+		}
+
+		selector := v1alpha1.ResourceSelector{
+			Kind:       o.Kind,
+			APIVersion: o.APIVersion,
+			Name:       o.NamePattern,
+		}
+		if len(o.LabelSelector) > 0 {
+			labelSelector, err := metav1.ParseToLabelSelector(o.LabelSelector)
+			if err != nil {
+				return fmt.Errorf("invalid label selector %s, %v", o.LabelSelector, err)
+			}
+			selector.LabelSelector = labelSelector
+		}
+		o.ResourceSelectors = []v1alpha1.ResourceSelector{selector}
+	} else {
+		err := json.Unmarshal([]byte(o.Target), &o.ResourceSelectors)
+		if err != nil {
+			return errors.New("please check the recommender target is valid")
+		}
 	}
 
-	if len(o.Name) == 0 {
-		return errors.New("please specify RecommendationRule name with --name")
+	recommendationRule, err := o.toRecommendationRule()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return ValidateRecommendationRule(recommendationRule)
 }
 
 func (o *RecommendationRuleCreateOptions) Complete(cmd *cobra.Command, args []string) error {
@@ -119,10 +179,31 @@ func (o *RecommendationRuleCreateOptions) Complete(cmd *cobra.Command, args []st
 		return err
 	}
 
+	// the rules in the file replace all the other flags, which is checked in Validate
+	if len(o.Filename) > 0 {
+		var err error
+		o.recommendationRules, err = loadRecommendationRules(o.Filename, o.CommonOptions.In)
+		return err
+	}
+
+	o.recommenderConfigs = map[string]map[string]string{}
+	if len(o.RecommenderConfigFile) > 0 {
+		configs, err := loadRecommenderConfigFile(o.RecommenderConfigFile)
+		if err != nil {
+			return err
+		}
+		mergeRecommenderConfigs(o.recommenderConfigs, configs)
+	}
+	configs, err := parseRecommenderConfigs(o.RecommenderConfigs)
+	if err != nil {
+		return err
+	}
+	mergeRecommenderConfigs(o.recommenderConfigs, configs)
+
 	return nil
 }
 
-func (o *RecommendationRuleCreateOptions) Run() error {
+func (o *RecommendationRuleCreateOptions) toRecommendationRule() (*v1alpha1.RecommendationRule, error) {
 	recommendationRule := &v1alpha1.RecommendationRule{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "RecommendationRule",
@@ -130,24 +211,45 @@ func (o *RecommendationRuleCreateOptions) Run() error {
 		},
 	}
 	recommendationRule.Namespace = ""
+	recommendationRule.Name = o.Name
 
-	if len(*o.CommonOptions.ConfigFlags.Namespace) == 0 || strings.EqualFold(*o.CommonOptions.ConfigFlags.Namespace, "Any") {
-		recommendationRule.Spec.NamespaceSelector.Any = true
-	} else {
-		recommendationRule.Spec.NamespaceSelector.MatchNames = strings.Split(*o.CommonOptions.ConfigFlags.Namespace, ",")
+	recommendationRule.Spec.NamespaceSelector = parseNamespaceSelector(*o.CommonOptions.ConfigFlags.Namespace)
+	recommendationRule.Spec.ResourceSelectors = o.ResourceSelectors
+	recommendationRule.Spec.Recommenders = parseRecommenders(o.Recommender)
+	recommendationRule.Spec.RunInterval = o.RunInterval
+
+	if err := applyRecommenderConfigs(recommendationRule.Spec.Recommenders, o.recommenderConfigs); err != nil {
+		return nil, err
 	}
 
-	recommendationRule.Spec.ResourceSelectors = o.ResourceSelectors
+	return recommendationRule, nil
+}
 
-	recommenders := strings.Split(o.Recommender, ",")
-	for _, recommender := range recommenders {
-		recommendationRule.Spec.Recommenders = append(recommendationRule.Spec.Recommenders, v1alpha1.Recommender{
-			Name: recommender,
-		})
+func (o *RecommendationRuleCreateOptions) Run() error {
+	if len(o.Filename) > 0 {
+		return o.apply()
 	}
 
-	recommendationRule.Spec.RunInterval = o.RunInterval
-	recommendationRule.Name = o.Name
+	recommendationRule, err := o.toRecommendationRule()
+	if err != nil {
+		return err
+	}
+
+	// the name patterns are expanded to the names of the matching targets once, the rule
+	// doesn't cover the targets created later even if they match the pattern
+	var resourceSelectors []v1alpha1.ResourceSelector
+	for _, selector := range recommendationRule.Spec.ResourceSelectors {
+		expanded, err := expandNamePattern(o.CommonOptions, selector, recommendationRule.Spec.NamespaceSelector)
+		if err != nil {
+			return err
+		}
+		resourceSelectors = append(resourceSelectors, expanded...)
+	}
+	recommendationRule.Spec.ResourceSelectors = resourceSelectors
+
+	if err := ValidateRecommendationRuleInCluster(o.CommonOptions, recommendationRule); err != nil {
+		return err
+	}
 
 	createOptions := metav1.CreateOptions{}
 	if o.DryRun {
@@ -175,10 +277,63 @@ func (o *RecommendationRuleCreateOptions) Run() error {
 	return nil
 }
 
+// apply creates or updates the recommendation rules loaded from the file with server-side apply,
+// so that applying the same file again is a no-op.
+func (o *RecommendationRuleCreateOptions) apply() error {
+	patchOptions := metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &o.ForceConflicts,
+	}
+	if o.DryRun {
+		patchOptions.DryRun = []string{"All"}
+	}
+
+	for _, recommendationRule := range o.recommendationRules {
+		if err := ValidateRecommendationRuleInCluster(o.CommonOptions, recommendationRule); err != nil {
+			return fmt.Errorf("invalid recommendation rule %s, %v", recommendationRule.Name, err)
+		}
+	}
+
+	for _, recommendationRule := range o.recommendationRules {
+		data, err := json.Marshal(recommendationRule)
+		if err != nil {
+			return err
+		}
+
+		applied, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Patch(context.TODO(), recommendationRule.Name, types.ApplyPatchType, data, patchOptions)
+		if err != nil {
+			return fmt.Errorf("failed to apply the recommendation rule %s, %v", recommendationRule.Name, err)
+		}
+
+		// when dry-run set, print the object
+		if o.DryRun {
+			applied.Kind = "RecommendationRule"
+			applied.APIVersion = "analysis.crane.io/v1alpha1"
+			printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+			if err = printer.PrintObj(applied, o.CommonOptions.Out); err != nil {
+				return err
+			}
+			continue
+		}
+
+		klog.Infof(fmt.Sprintf("the recommendation rule %s applied successfully", recommendationRule.Name))
+	}
+
+	return nil
+}
+
 func (o *RecommendationRuleCreateOptions) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.Recommender, "recommender", "", "Resource", "specify type for recommendationrules，separated with ',' if more than one, default is Resource")
 	cmd.Flags().StringVarP(&o.Target, "target", "", "", "specify recommend target for recommendationrules")
 	cmd.Flags().StringVarP(&o.RunInterval, "run-interval", "", "", "Specify runInterval for recommendationrules")
 	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
 	cmd.Flags().StringVarP(&o.Name, "name", "", "", "recommendationrule name")
+	cmd.Flags().StringArrayVarP(&o.RecommenderConfigs, "recommender-config", "", nil, "Specify the option of a recommender as <recommender>.<option>=<value>, e.g. Resource.cpu-request-percentile=0.95, can be repeated")
+	cmd.Flags().StringVarP(&o.RecommenderConfigFile, "recommender-config-file", "", "", "The yaml file containing the options of each recommender, the options of --recommender-config take precedence")
+	cmd.Flags().StringVarP(&o.Filename, "filename", "f", "", "The file containing the recommendationrules to create or update with server-side apply, '-' reads from stdin")
+	cmd.Flags().BoolVarP(&o.ForceConflicts, "force-conflicts", "", false, "Take the ownership of the fields managed by others when applying the file")
+	cmd.Flags().StringVarP(&o.Kind, "kind", "", "", "Specify the kind of recommend target, e.g. Deployment")
+	cmd.Flags().StringVarP(&o.APIVersion, "api-version", "", "", "Specify the api version of recommend target, e.g. apps/v1")
+	cmd.Flags().StringVarP(&o.LabelSelector, "label-selector", "", "", "Specify the label selector of recommend target, e.g. app=web,tier!=cache")
+	cmd.Flags().StringVarP(&o.NamePattern, "name-pattern", "", "", "Specify the name of recommend target, wildcards like 'web-*' are expanded once to the names of the targets existing at creation, the targets created later are not covered, use --label-selector to cover them")
 }
@@ -0,0 +1,165 @@
+package recommendationRule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+)
+
+var (
+	recommendationRuleDeleteExample = `
+# delete the specified recommendation rule and keep its recommendations
+%[1]s rr delete workloads-rule
+
+# delete the specified recommendation rule together with its recommendations
+%[1]s rr delete workloads-rule --cascade
+
+# pre-commit
+%[1]s rr delete workloads-rule --cascade --dry-run
+
+# succeed even if the recommendation rule is already deleted
+%[1]s rr delete workloads-rule --cascade --ignore-not-found
+`
+)
+
+type RecommendationRuleDeleteOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Name           string
+	Cascade        bool
+	DryRun         bool
+	IgnoreNotFound bool
+}
+
+func NewRecommendationRuleDeleteOptions() *RecommendationRuleDeleteOptions {
+	return &RecommendationRuleDeleteOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdRecommendationRuleDelete() *cobra.Command {
+	o := NewRecommendationRuleDeleteOptions()
+
+	command := &cobra.Command{
+		Use:     "delete <rule>",
+		Short:   "delete a recommendation rule",
+		Example: fmt.Sprintf(recommendationRuleDeleteExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendationRuleDeleteExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *RecommendationRuleDeleteOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommendation rule name")
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleDeleteOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleDeleteOptions) Run() error {
+	deleteOptions := metav1.DeleteOptions{}
+	if o.DryRun {
+		deleteOptions.DryRun = []string{"All"}
+	}
+
+	// the recommendations are owned by the rule, orphan them unless cascade is set
+	propagationPolicy := metav1.DeletePropagationOrphan
+	if o.Cascade {
+		propagationPolicy = metav1.DeletePropagationBackground
+	}
+	deleteOptions.PropagationPolicy = &propagationPolicy
+
+	err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Delete(context.TODO(), o.Name, deleteOptions)
+	switch {
+	case apierrors.IsNotFound(err) && o.IgnoreNotFound:
+		klog.Infof("the recommendation rule %s is not found", o.Name)
+	case err != nil:
+		return fmt.Errorf("failed to delete the recommendation rule %s, %v", o.Name, err)
+	default:
+		klog.Infof(fmt.Sprintf("the recommendation rule %s deleted successfully", o.Name))
+	}
+
+	if !o.Cascade {
+		return nil
+	}
+
+	// delete the recommendations labelled with the rule name explicitly as well,
+	// so that they are gone even if they don't carry the owner reference.
+	filterOptions := recommend.NewRecommendFilterOptions()
+	filterOptions.RuleName = o.Name
+	filterOptions.AllNamespaces = true
+	recommendations, err := filterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return err
+	}
+
+	// the garbage collector deletes the recommendations owned by the rule in the meantime,
+	// so the recommendations not found are skipped, and the failed ones don't stop the others.
+	deleted, failed := 0, 0
+	for _, recommendation := range recommendations {
+		err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(recommendation.Namespace).Delete(context.TODO(), recommendation.Name, deleteOptions)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Failed to delete the recommendation %s/%s, %v.", recommendation.Namespace, recommendation.Name, err)
+			failed++
+			continue
+		}
+		deleted++
+	}
+	klog.Infof("%d recommendations of the recommendation rule %s deleted", deleted, o.Name)
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d recommendations of the recommendation rule %s", failed, o.Name)
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleDeleteOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&o.Cascade, "cascade", "", false, "Delete the recommendations of the recommendation rule as well")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+	cmd.Flags().BoolVarP(&o.IgnoreNotFound, "ignore-not-found", "", false, "Treat the recommendation rule not found as deleted, the recommendations not found are always skipped")
+}
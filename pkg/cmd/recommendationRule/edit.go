@@ -0,0 +1,221 @@
+package recommendationRule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+var (
+	recommendationRuleEditExample = `
+# edit the specified recommendation rule with the editor in KUBE_EDITOR or EDITOR
+%[1]s rr edit workloads-rule
+
+# edit the specified recommendation rule with nano
+KUBE_EDITOR=nano %[1]s rr edit workloads-rule
+`
+)
+
+const (
+	defaultEditor = "vi"
+
+	editHeader = `# Please edit the recommendation rule below. Lines beginning with a '#' will be ignored,
+# and an empty file will abort the edit. If an error occurs while saving this file will be
+# reopened with the relevant failures.
+#
+`
+)
+
+type RecommendationRuleEditOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Name string
+}
+
+func NewRecommendationRuleEditOptions() *RecommendationRuleEditOptions {
+	return &RecommendationRuleEditOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdRecommendationRuleEdit() *cobra.Command {
+	o := NewRecommendationRuleEditOptions()
+
+	command := &cobra.Command{
+		Use:     "edit <rule>",
+		Short:   "edit a recommendation rule",
+		Example: fmt.Sprintf(recommendationRuleEditExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendationRuleEditExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+
+	return command
+}
+
+func (o *RecommendationRuleEditOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommendation rule name")
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleEditOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleEditOptions) Run() error {
+	recommendationRule, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the recommendation rule %s, %v", o.Name, err)
+	}
+
+	recommendationRule.Kind = "RecommendationRule"
+	recommendationRule.APIVersion = "analysis.crane.io/v1alpha1"
+	recommendationRule.ManagedFields = nil
+	original, err := yaml.Marshal(recommendationRule)
+	if err != nil {
+		return err
+	}
+
+	content := append([]byte(editHeader), original...)
+	var lastInvalid []byte
+	for {
+		edited, err := o.launchEditor(content)
+		if err != nil {
+			return err
+		}
+
+		body := stripHeaderComments(edited)
+		if len(bytes.TrimSpace(body)) == 0 {
+			klog.Infof("Edit cancelled, saved file was empty.")
+			return nil
+		}
+		if bytes.Equal(body, original) {
+			klog.Infof("Edit cancelled, no changes made.")
+			return nil
+		}
+
+		editedRule := &v1alpha1.RecommendationRule{}
+		err = yaml.UnmarshalStrict(body, editedRule)
+		if err != nil && yaml.UnmarshalStrict(original, &v1alpha1.RecommendationRule{}) != nil {
+			// the fields unknown to this client were in the rule before the edit
+			err = yaml.Unmarshal(body, editedRule)
+		}
+		if err == nil && editedRule.Name != recommendationRule.Name {
+			err = errors.New("the name of the recommendation rule can't be changed")
+		}
+		if err == nil {
+			err = ValidateRecommendationRuleUpdate(recommendationRule, editedRule)
+		}
+		if err == nil {
+			err = ValidateRecommendationRuleInCluster(o.CommonOptions, editedRule)
+		}
+		if err != nil {
+			// the same invalid content saved twice means the user gives up
+			if bytes.Equal(body, lastInvalid) {
+				return fmt.Errorf("the edited recommendation rule is invalid, %v", err)
+			}
+			lastInvalid = body
+			content = append([]byte(editHeader+"# error: "+strings.ReplaceAll(err.Error(), "\n", "\n# ")+"\n#\n"), body...)
+			continue
+		}
+
+		// the resource version of the edited rule makes the update fail if the rule changed meanwhile
+		if _, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Update(context.TODO(), editedRule, metav1.UpdateOptions{}); err != nil {
+			if apierrors.IsConflict(err) {
+				return fmt.Errorf("the recommendation rule %s has been modified since it was opened, please edit it again, %v", o.Name, err)
+			}
+			return fmt.Errorf("failed to update the recommendation rule %s, %v", o.Name, err)
+		}
+
+		klog.Infof(fmt.Sprintf("the recommendation rule %s edited successfully", o.Name))
+		return nil
+	}
+}
+
+// launchEditor opens the content in the editor of KUBE_EDITOR, EDITOR or vi and returns the saved content
+func (o *RecommendationRuleEditOptions) launchEditor(content []byte) ([]byte, error) {
+	file, err := os.CreateTemp("", "kubectl-crane-edit-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("KUBE_EDITOR")
+	if len(editor) == 0 {
+		editor = os.Getenv("EDITOR")
+	}
+	if len(editor) == 0 {
+		editor = defaultEditor
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+	cmd.Stdin = o.CommonOptions.In
+	cmd.Stdout = o.CommonOptions.Out
+	cmd.Stderr = o.CommonOptions.ErrOut
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to launch the editor %s, %v", editor, err)
+	}
+
+	return os.ReadFile(file.Name())
+}
+
+// stripHeaderComments removes the comment lines at the beginning of the content
+func stripHeaderComments(content []byte) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for len(lines) > 0 && bytes.HasPrefix(bytes.TrimSpace(lines[0]), []byte("#")) {
+		lines = lines[1:]
+	}
+
+	return bytes.Join(lines, nil)
+}
@@ -0,0 +1,110 @@
+package recommendationRule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+var (
+	recommendationRuleGetExample = `
+# view the specified recommendation rule
+%[1]s rr get workloads-rule
+
+# output the specified recommendation rule as yaml
+%[1]s rr get workloads-rule -o yaml
+`
+)
+
+type RecommendationRuleGetOptions struct {
+	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
+
+	Name string
+}
+
+func NewRecommendationRuleGetOptions() *RecommendationRuleGetOptions {
+	return &RecommendationRuleGetOptions{
+		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
+	}
+}
+
+func NewCmdRecommendationRuleGet() *cobra.Command {
+	o := NewRecommendationRuleGetOptions()
+
+	command := &cobra.Command{
+		Use:     "get <rule>",
+		Short:   "view a recommendation rule",
+		Example: fmt.Sprintf(recommendationRuleGetExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendationRuleGetExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.PrintOptions.AddPrintFlags(command)
+
+	return command
+}
+
+func (o *RecommendationRuleGetOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommendation rule name")
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleGetOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleGetOptions) Run() error {
+	recommendationRule, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the recommendation rule %s, %v", o.Name, err)
+	}
+
+	if !o.PrintOptions.IsTable() {
+		return o.PrintOptions.PrintObj(recommendationRule, o.CommonOptions.Out)
+	}
+
+	renderTable([]analysisv1alph1.RecommendationRule{*recommendationRule}, o.CommonOptions.Out, o.PrintOptions.IsWide())
+	return nil
+}
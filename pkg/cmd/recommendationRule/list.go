@@ -2,12 +2,16 @@ package recommendationRule
 
 import (
 	"context"
+	"io"
 	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
@@ -17,14 +21,17 @@ import (
 
 type RecommendationRuleListOptions struct {
 	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
 
 	Name        string
 	Recommender string
+	Watch       bool
 }
 
 func NewRecommendationRuleListOptions() *RecommendationRuleListOptions {
 	return &RecommendationRuleListOptions{
 		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
 	}
 }
 
@@ -50,6 +57,7 @@ func NewCmdRecommendationRuleList() *cobra.Command {
 		},
 	}
 	o.CommonOptions.AddCommonFlag(command)
+	o.PrintOptions.AddPrintFlags(command)
 	o.AddFlags(command)
 
 	return command
@@ -60,6 +68,10 @@ func (o *RecommendationRuleListOptions) Validate() error {
 		return err
 	}
 
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -72,9 +84,8 @@ func (o *RecommendationRuleListOptions) Complete(cmd *cobra.Command, args []stri
 }
 
 func (o *RecommendationRuleListOptions) Run() error {
-	query := utils.NewQuery()
-	if len(o.Name) > 0 {
-		query.Filters[utils.FieldName] = utils.Value(o.Name)
+	if o.Watch {
+		return o.watch()
 	}
 
 	recommendationRuleResult, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().List(context.TODO(), metav1.ListOptions{})
@@ -84,41 +95,53 @@ func (o *RecommendationRuleListOptions) Run() error {
 	}
 	var recommendationRules []analysisv1alph1.RecommendationRule
 	for _, recommendationRule := range recommendationRuleResult.Items {
-		selected := true
-		for field, value := range query.Filters {
-			if !utils.ObjectMetaFilter(recommendationRule.ObjectMeta, utils.Filter{Field: field, Value: value}) {
-				selected = false
-				break
-			}
-		}
-		if selected && len(o.Recommender) > 0 {
-			for _, recommender := range recommendationRule.Spec.Recommenders {
-				if !strings.EqualFold(recommender.Name, o.Recommender) {
-					selected = false
-				} else {
-					selected = true
-					break
-				}
-			}
-		}
-
-		if selected {
+		if o.selected(recommendationRule) {
 			recommendationRules = append(recommendationRules, recommendationRule)
 		}
 	}
 
-	o.renderTable(recommendationRules)
+	if !o.PrintOptions.IsTable() {
+		return o.PrintOptions.PrintObj(&analysisv1alph1.RecommendationRuleList{Items: recommendationRules}, o.CommonOptions.Out)
+	}
+
+	renderTable(recommendationRules, o.CommonOptions.Out, o.PrintOptions.IsWide())
 
 	return nil
 }
 
-func (o *RecommendationRuleListOptions) renderTable(recommendationRules []analysisv1alph1.RecommendationRule) {
+// selected returns true when the recommendation rule matches the name and the recommender
+func (o *RecommendationRuleListOptions) selected(recommendationRule analysisv1alph1.RecommendationRule) bool {
+	query := utils.NewQuery()
+	if len(o.Name) > 0 {
+		query.Filters[utils.FieldName] = utils.Value(o.Name)
+	}
+
+	selected := true
+	for field, value := range query.Filters {
+		if !utils.ObjectMetaFilter(recommendationRule.ObjectMeta, utils.Filter{Field: field, Value: value}) {
+			selected = false
+			break
+		}
+	}
+	if selected && len(o.Recommender) > 0 {
+		for _, recommender := range recommendationRule.Spec.Recommenders {
+			if !strings.EqualFold(recommender.Name, o.Recommender) {
+				selected = false
+			} else {
+				selected = true
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+func renderTable(recommendationRules []analysisv1alph1.RecommendationRule, out io.Writer, wide bool) {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
-	t.SetOutputMirror(o.CommonOptions.Out)
-	header := table.Row{}
-	header = append(header, table.Row{"NAME", "RECOMMENDER", "TARGET", "NAMESPACE", "RUN INTERVAL", "LAST UPDATE TIME", "CREATE TIME"}...)
-	t.AppendHeader(header)
+	t.SetOutputMirror(out)
+	t.AppendHeader(tableHeader(wide))
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{
 			Name:        "NAME",
@@ -132,46 +155,107 @@ func (o *RecommendationRuleListOptions) renderTable(recommendationRules []analys
 	})
 
 	for _, recommendRule := range recommendationRules {
-		row := table.Row{}
+		t.AppendRows([]table.Row{
+			tableRow(recommendRule, wide),
+		})
+
+		t.AppendSeparator()
+	}
 
-		row = append(row, recommendRule.Name)
+	t.Render()
+}
 
-		var recommenders []string
-		for _, recommender := range recommendRule.Spec.Recommenders {
-			recommenders = append(recommenders, recommender.Name)
-		}
-		row = append(row, strings.Join(recommenders, ","))
+// watch prints the recommendation rules and then their changes one by one until interrupted
+func (o *RecommendationRuleListOptions) watch() error {
+	wide := o.PrintOptions.IsWide()
+	rowPrinter := utils.NewRowPrinter(o.CommonOptions.Out, append(table.Row{"EVENT"}, tableHeader(wide)...))
 
-		var targets []string
-		for _, resourceSelector := range recommendRule.Spec.ResourceSelectors {
-			targets = append(targets, resourceSelector.Kind)
+	client := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return client.List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.Watch(context.TODO(), options)
+		},
+	}
+
+	utils.WatchUntilInterrupted(context.TODO(), lw, &analysisv1alph1.RecommendationRule{}, func(eventType string, obj interface{}) {
+		recommendationRule, ok := obj.(*analysisv1alph1.RecommendationRule)
+		if !ok || !o.selected(*recommendationRule) {
+			return
 		}
-		row = append(row, strings.Join(targets, ","))
 
-		var namespaces []string
-		if recommendRule.Spec.NamespaceSelector.Any {
-			namespaces = append(namespaces, "Any")
+		var err error
+		if o.PrintOptions.IsTable() {
+			err = rowPrinter.PrintRow(append(table.Row{eventType}, tableRow(*recommendationRule, wide)...))
 		} else {
-			namespaces = append(namespaces, recommendRule.Spec.NamespaceSelector.MatchNames...)
+			err = o.PrintOptions.PrintObj(recommendationRule, o.CommonOptions.Out)
+		}
+		if err != nil {
+			klog.Errorf("Failed to print recommendation rule %s, %v.", recommendationRule.Name, err)
 		}
+	})
 
-		row = append(row, strings.Join(namespaces, ","))
+	return nil
+}
 
-		row = append(row, recommendRule.Spec.RunInterval)
-		row = append(row, recommendRule.Status.LastUpdateTime)
-		row = append(row, recommendRule.CreationTimestamp)
+func tableHeader(wide bool) table.Row {
+	header := table.Row{}
+	header = append(header, table.Row{"NAME", "RECOMMENDER", "TARGET", "NAMESPACE", "RUN INTERVAL", "LAST UPDATE TIME", "CREATE TIME"}...)
+	if wide {
+		header = append(header, table.Row{"RUN NUMBER", "TARGET API VERSION", "RECOMMENDATIONS"}...)
+	}
 
-		t.AppendRows([]table.Row{
-			row,
-		})
+	return header
+}
 
-		t.AppendSeparator()
+func tableRow(recommendRule analysisv1alph1.RecommendationRule, wide bool) table.Row {
+	row := table.Row{}
+
+	row = append(row, recommendRule.Name)
+
+	var recommenders []string
+	for _, recommender := range recommendRule.Spec.Recommenders {
+		recommenders = append(recommenders, recommender.Name)
 	}
+	row = append(row, strings.Join(recommenders, ","))
 
-	t.Render()
+	var targets []string
+	for _, resourceSelector := range recommendRule.Spec.ResourceSelectors {
+		targets = append(targets, resourceSelector.Kind)
+	}
+	row = append(row, strings.Join(targets, ","))
+
+	var namespaces []string
+	if recommendRule.Spec.NamespaceSelector.Any {
+		namespaces = append(namespaces, "Any")
+	} else {
+		namespaces = append(namespaces, recommendRule.Spec.NamespaceSelector.MatchNames...)
+	}
+
+	row = append(row, strings.Join(namespaces, ","))
+
+	row = append(row, recommendRule.Spec.RunInterval)
+	row = append(row, recommendRule.Status.LastUpdateTime)
+	row = append(row, recommendRule.CreationTimestamp)
+
+	if wide {
+		row = append(row, recommendRule.Status.RunNumber)
+
+		var apiVersions []string
+		for _, resourceSelector := range recommendRule.Spec.ResourceSelectors {
+			apiVersions = append(apiVersions, resourceSelector.APIVersion)
+		}
+		row = append(row, strings.Join(apiVersions, ","))
+		row = append(row, len(recommendRule.Status.Recommendations))
+	}
+
+	return row
 }
 
 func (o *RecommendationRuleListOptions) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&o.Name, "name", "", "", "Specify name for recommendationrules")
 	cmd.Flags().StringVarP(&o.Recommender, "recommender", "", "", "Specify type for recommendationrules")
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "After listing the recommendation rules, watch for changes")
 }
@@ -0,0 +1,235 @@
+package recommendationRule
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/gocrane/api/analysis/v1alpha1"
+)
+
+// recommenderOptionType describes how the value of a recommender option is validated
+type recommenderOptionType string
+
+const (
+	// a float in (0, 1]
+	optionTypePercentile recommenderOptionType = "percentile"
+	// a non-negative float
+	optionTypeFloat recommenderOptionType = "float"
+	// a non-negative integer
+	optionTypeInt recommenderOptionType = "int"
+	// a go duration, e.g. 168h
+	optionTypeDuration recommenderOptionType = "duration"
+	optionTypeBool     recommenderOptionType = "bool"
+	optionTypeString   recommenderOptionType = "string"
+)
+
+// replicasRecommenderOptions are shared by the Replicas and HPA recommenders
+var replicasRecommenderOptions = map[string]recommenderOptionType{
+	"workload-min-replicas":      optionTypeInt,
+	"pod-min-ready-seconds":      optionTypeInt,
+	"pod-available-ratio":        optionTypePercentile,
+	"default-min-replicas":       optionTypeInt,
+	"cpu-percentile":             optionTypePercentile,
+	"mem-percentile":             optionTypePercentile,
+	"max-replicas-factor":        optionTypeFloat,
+	"min-cpu-usage-threshold":    optionTypeFloat,
+	"fluctuation-threshold":      optionTypeFloat,
+	"min-cpu-target-utilization": optionTypeInt,
+	"max-cpu-target-utilization": optionTypeInt,
+	"cpu-target-utilization":     optionTypeInt,
+	"history-length":             optionTypeDuration,
+}
+
+// recommenderOptionSchema is the schema of the known options of each recommender
+var recommenderOptionSchema = map[string]map[string]recommenderOptionType{
+	v1alpha1.ResourceRecommender: {
+		"cpu-sample-interval":         optionTypeDuration,
+		"cpu-request-percentile":      optionTypePercentile,
+		"cpu-request-margin-fraction": optionTypeFloat,
+		"cpu-target-utilization":      optionTypePercentile,
+		"cpu-model-history-length":    optionTypeDuration,
+		"cpu-histogram-bucket-size":   optionTypeFloat,
+		"cpu-histogram-max-value":     optionTypeFloat,
+		"mem-sample-interval":         optionTypeDuration,
+		"mem-request-percentile":      optionTypePercentile,
+		"mem-request-margin-fraction": optionTypeFloat,
+		"mem-target-utilization":      optionTypePercentile,
+		"mem-model-history-length":    optionTypeDuration,
+		"mem-histogram-bucket-size":   optionTypeFloat,
+		"mem-histogram-max-value":     optionTypeFloat,
+		"oom-protection":              optionTypeBool,
+		"oom-history-length":          optionTypeDuration,
+		"oom-bump-ratio":              optionTypeFloat,
+		"specification":               optionTypeBool,
+		"specification-config":        optionTypeString,
+	},
+	v1alpha1.ReplicasRecommender: replicasRecommenderOptions,
+	v1alpha1.HPARecommender:      mergeOptions(replicasRecommenderOptions, map[string]recommenderOptionType{"reference-hpa": optionTypeBool}),
+	v1alpha1.IdleNodeRecommender: {
+		"cpu-request-utilization":    optionTypePercentile,
+		"cpu-usage-utilization":      optionTypePercentile,
+		"cpu-percentile":             optionTypePercentile,
+		"memory-request-utilization": optionTypePercentile,
+		"memory-usage-utilization":   optionTypePercentile,
+		"memory-percentile":          optionTypePercentile,
+	},
+}
+
+func mergeOptions(base, extra map[string]recommenderOptionType) map[string]recommenderOptionType {
+	merged := map[string]recommenderOptionType{}
+	for key, optionType := range base {
+		merged[key] = optionType
+	}
+	for key, optionType := range extra {
+		merged[key] = optionType
+	}
+
+	return merged
+}
+
+// parseRecommenderConfigs parses the options like Resource.cpu-request-percentile=0.95 into the configs of each recommender
+func parseRecommenderConfigs(recommenderConfigs []string) (map[string]map[string]string, error) {
+	configs := map[string]map[string]string{}
+	for _, recommenderConfig := range recommenderConfigs {
+		keyValue := strings.SplitN(recommenderConfig, "=", 2)
+		key := strings.SplitN(keyValue[0], ".", 2)
+		if len(keyValue) != 2 || len(key) != 2 || len(key[0]) == 0 || len(key[1]) == 0 {
+			return nil, fmt.Errorf("invalid recommender config %s, please specify it as <recommender>.<option>=<value>", recommenderConfig)
+		}
+		recommender, option, value := key[0], key[1], keyValue[1]
+
+		if configs[recommender] == nil {
+			configs[recommender] = map[string]string{}
+		}
+		configs[recommender][option] = value
+	}
+
+	return configs, nil
+}
+
+// loadRecommenderConfigFile reads the configs of each recommender from a yaml file like:
+//
+//	Resource:
+//	  cpu-request-percentile: "0.95"
+//	  oom-protection: "true"
+func loadRecommenderConfigFile(filename string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := map[string]map[string]string{}
+	if err := yaml.UnmarshalStrict(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to decode the recommender config file %s, %v", filename, err)
+	}
+
+	return configs, nil
+}
+
+// mergeRecommenderConfigs merges the configs into dst, the options in configs take precedence
+func mergeRecommenderConfigs(dst, configs map[string]map[string]string) {
+	for recommender, config := range configs {
+		if dst[recommender] == nil {
+			dst[recommender] = map[string]string{}
+		}
+		for option, value := range config {
+			dst[recommender][option] = value
+		}
+	}
+}
+
+// applyRecommenderConfigs sets the configs to the recommenders, the configs of a recommender not in use is an error
+func applyRecommenderConfigs(recommenders []v1alpha1.Recommender, configs map[string]map[string]string) error {
+	for name, config := range configs {
+		found := false
+		for i := range recommenders {
+			if recommenders[i].Name != name {
+				continue
+			}
+			found = true
+
+			if recommenders[i].Config == nil {
+				recommenders[i].Config = map[string]string{}
+			}
+			for option, value := range config {
+				recommenders[i].Config[option] = value
+			}
+		}
+		if !found {
+			return fmt.Errorf("the recommender %s is configured but not specified with --recommender", name)
+		}
+	}
+
+	return nil
+}
+
+// validateRecommenderConfig checks the config of the recommender against the schema of the known options
+func validateRecommenderConfig(recommender v1alpha1.Recommender) error {
+	schema := recommenderOptionSchema[recommender.Name]
+
+	options := make([]string, 0, len(recommender.Config))
+	for option := range recommender.Config {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	for _, option := range options {
+		optionType, ok := schema[option]
+		if !ok {
+			return fmt.Errorf("unknown option %s of the recommender %s, supported options: %s", option, recommender.Name, strings.Join(knownOptions(recommender.Name), ", "))
+		}
+
+		if err := validateOptionValue(optionType, recommender.Config[option]); err != nil {
+			return fmt.Errorf("invalid value of the option %s.%s, %v", recommender.Name, option, err)
+		}
+	}
+
+	return nil
+}
+
+func validateOptionValue(optionType recommenderOptionType, value string) error {
+	switch optionType {
+	case optionTypePercentile:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f <= 0 || f > 1 {
+			return fmt.Errorf("%q is not a number in (0, 1]", value)
+		}
+	case optionTypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("%q is not a non-negative number", value)
+		}
+	case optionTypeInt:
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 {
+			return fmt.Errorf("%q is not a non-negative integer", value)
+		}
+	case optionTypeDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%q is not a positive duration like 168h", value)
+		}
+	case optionTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	}
+
+	return nil
+}
+
+func knownOptions(recommender string) []string {
+	var options []string
+	for option := range recommenderOptionSchema[recommender] {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	return options
+}
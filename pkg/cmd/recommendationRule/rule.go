@@ -0,0 +1,342 @@
+package recommendationRule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+const (
+	// FieldManager is the field manager of the fields applied by kubectl-crane
+	FieldManager = "kubectl-crane"
+
+	// MinRunInterval and MaxRunInterval are the bounds of the run interval of the recommendation rules
+	MinRunInterval = time.Minute
+	MaxRunInterval = 30 * 24 * time.Hour
+)
+
+// parseRecommenders parses the recommenders separated with ','
+func parseRecommenders(recommender string) []v1alpha1.Recommender {
+	var recommenders []v1alpha1.Recommender
+	for _, name := range strings.Split(recommender, ",") {
+		recommenders = append(recommenders, v1alpha1.Recommender{
+			Name: strings.TrimSpace(name),
+		})
+	}
+
+	return recommenders
+}
+
+// parseNamespaceSelector parses the namespaces separated with ',', empty or Any selects all namespaces
+func parseNamespaceSelector(namespace string) v1alpha1.NamespaceSelector {
+	if len(namespace) == 0 || strings.EqualFold(namespace, "Any") {
+		return v1alpha1.NamespaceSelector{Any: true}
+	}
+
+	return v1alpha1.NamespaceSelector{MatchNames: strings.Split(namespace, ",")}
+}
+
+// ValidateRecommendationRule ensures the recommendation rule is valid before it is submitted
+func ValidateRecommendationRule(rule *v1alpha1.RecommendationRule) error {
+	return validateRecommendationRule(rule, nil)
+}
+
+// ValidateRecommendationRuleUpdate validates the updated recommendation rule like ValidateRecommendationRule,
+// except that the run interval and the recommender options kept from the existing rule are only warned about,
+// the rules applied by other tools may use values this client doesn't know.
+func ValidateRecommendationRuleUpdate(existing, rule *v1alpha1.RecommendationRule) error {
+	return validateRecommendationRule(rule, existing)
+}
+
+func validateRecommendationRule(rule *v1alpha1.RecommendationRule, existing *v1alpha1.RecommendationRule) error {
+	if len(rule.Name) == 0 {
+		return errors.New("please specify RecommendationRule name with --name")
+	}
+
+	if len(rule.Spec.ResourceSelectors) == 0 {
+		return errors.New("please check the recommender target is valid")
+	}
+	for _, selector := range rule.Spec.ResourceSelectors {
+		if len(selector.Kind) == 0 {
+			return errors.New("please specify the kind of the recommender target")
+		}
+	}
+
+	if len(rule.Spec.Recommenders) == 0 {
+		return errors.New("please specify the recommenders of RecommendationRule")
+	}
+	for _, recommender := range rule.Spec.Recommenders {
+		typeExist := false
+		for _, recommenderType := range v1alpha1.AllRecommenderType {
+			if recommenderType == recommender.Name {
+				typeExist = true
+			}
+		}
+		if !typeExist {
+			return fmt.Errorf("the recommender type not supported %s", recommender.Name)
+		}
+		changed, kept := splitRecommenderConfig(recommender, existing)
+		if err := validateRecommenderConfig(changed); err != nil {
+			return err
+		}
+		if err := validateRecommenderConfig(kept); err != nil {
+			klog.Warningf("The existing config of the recommendation rule %s is kept, %v.", rule.Name, err)
+		}
+	}
+
+	if len(rule.Spec.RunInterval) == 0 {
+		return errors.New("please specify the runInterval with --run-interval")
+	}
+	if err := validateRunInterval(rule.Spec.RunInterval); err != nil {
+		if existing == nil || existing.Spec.RunInterval != rule.Spec.RunInterval {
+			return err
+		}
+		klog.Warningf("The existing runInterval of the recommendation rule %s is kept, %v.", rule.Name, err)
+	}
+
+	if !rule.Spec.NamespaceSelector.Any && len(rule.Spec.NamespaceSelector.MatchNames) == 0 {
+		return errors.New("please specify the namespaces of RecommendationRule")
+	}
+
+	return nil
+}
+
+func validateRunInterval(interval string) error {
+	runInterval, err := time.ParseDuration(interval)
+	if err != nil {
+		return fmt.Errorf("invalid runInterval %s, please specify a duration like 4h, %v", interval, err)
+	}
+	if runInterval < MinRunInterval || runInterval > MaxRunInterval {
+		return fmt.Errorf("invalid runInterval %s, it should be between %s and %s", interval, MinRunInterval, MaxRunInterval)
+	}
+
+	return nil
+}
+
+// splitRecommenderConfig splits the options of the recommender into the ones changed from the existing rule
+// and the ones kept, all the options are changed when there is no existing rule.
+func splitRecommenderConfig(recommender v1alpha1.Recommender, existing *v1alpha1.RecommendationRule) (v1alpha1.Recommender, v1alpha1.Recommender) {
+	changed := v1alpha1.Recommender{Name: recommender.Name, Config: map[string]string{}}
+	kept := v1alpha1.Recommender{Name: recommender.Name, Config: map[string]string{}}
+
+	var existingConfig map[string]string
+	if existing != nil {
+		for _, existingRecommender := range existing.Spec.Recommenders {
+			if existingRecommender.Name == recommender.Name {
+				existingConfig = existingRecommender.Config
+			}
+		}
+	}
+
+	for option, value := range recommender.Config {
+		if existingValue, exist := existingConfig[option]; exist && existingValue == value {
+			kept.Config[option] = value
+		} else {
+			changed.Config[option] = value
+		}
+	}
+
+	return changed, kept
+}
+
+// loadRecommendationRules decodes the recommendation rules in the yaml or json file, '-' reads from in
+func loadRecommendationRules(filename string, in io.Reader) ([]*v1alpha1.RecommendationRule, error) {
+	reader := in
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var recommendationRules []*v1alpha1.RecommendationRule
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		recommendationRule := &v1alpha1.RecommendationRule{}
+		if err := decoder.Decode(recommendationRule); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode %s, %v", filename, err)
+		}
+
+		// skip the empty documents
+		if len(recommendationRule.Kind) == 0 && len(recommendationRule.Name) == 0 {
+			continue
+		}
+		if recommendationRule.Kind != "RecommendationRule" {
+			return nil, fmt.Errorf("unexpected kind %s in %s, only RecommendationRule is supported", recommendationRule.Kind, filename)
+		}
+		if len(recommendationRule.APIVersion) == 0 {
+			recommendationRule.APIVersion = v1alpha1.SchemeGroupVersion.String()
+		}
+
+		recommendationRules = append(recommendationRules, recommendationRule)
+	}
+
+	if len(recommendationRules) == 0 {
+		return nil, fmt.Errorf("no recommendation rule found in %s", filename)
+	}
+
+	return recommendationRules, nil
+}
+
+// isNamePattern returns true if the name contains wildcards of filepath.Match
+func isNamePattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// expandNamePattern replaces a selector whose name is a wildcard pattern with the selectors of
+// the existing targets matching the pattern in the selected namespaces. The expansion is a snapshot,
+// the rule doesn't select the targets created afterwards.
+func expandNamePattern(commonOptions *options.CommonOptions, selector v1alpha1.ResourceSelector, namespaceSelector v1alpha1.NamespaceSelector) ([]v1alpha1.ResourceSelector, error) {
+	if !isNamePattern(selector.Name) {
+		return []v1alpha1.ResourceSelector{selector}, nil
+	}
+	if _, err := filepath.Match(selector.Name, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern %s, %v", selector.Name, err)
+	}
+
+	gvr, err := utils.GetGroupVersionResource(commonOptions.DiscoveryClient, selector.APIVersion, selector.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the resource of %s %s, %v", selector.APIVersion, selector.Kind, err)
+	}
+
+	namespaces := namespaceSelector.MatchNames
+	if namespaceSelector.Any {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	listOptions := metav1.ListOptions{}
+	if selector.LabelSelector != nil {
+		listOptions.LabelSelector = metav1.FormatLabelSelector(selector.LabelSelector)
+	}
+
+	var selectors []v1alpha1.ResourceSelector
+	seen := map[string]bool{}
+	for _, namespace := range namespaces {
+		targets, err := commonOptions.DynamicClient.Resource(*gvr).Namespace(namespace).List(context.TODO(), listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s, %v", gvr.Resource, err)
+		}
+
+		for _, target := range targets.Items {
+			if matched, _ := filepath.Match(selector.Name, target.GetName()); !matched || seen[target.GetName()] {
+				continue
+			}
+			seen[target.GetName()] = true
+
+			expanded := selector
+			expanded.Name = target.GetName()
+			selectors = append(selectors, expanded)
+		}
+	}
+
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("no %s matches the name pattern %s", selector.Kind, selector.Name)
+	}
+	klog.Warningf("the name pattern %s is expanded to %d existing %s, the ones created later won't be selected", selector.Name, len(selectors), selector.Kind)
+
+	return selectors, nil
+}
+
+// ValidateRecommendationRuleInCluster ensures the targets and the namespaces of the recommendation rule exist in the cluster,
+// and warns about the existing recommendation rules selecting the same targets.
+func ValidateRecommendationRuleInCluster(commonOptions *options.CommonOptions, rule *v1alpha1.RecommendationRule) error {
+	for _, selector := range rule.Spec.ResourceSelectors {
+		if len(selector.APIVersion) == 0 {
+			return fmt.Errorf("please specify the apiVersion of the recommender target %s", selector.Kind)
+		}
+		if _, err := utils.GetGroupVersionResource(commonOptions.DiscoveryClient, selector.APIVersion, selector.Kind); err != nil {
+			return fmt.Errorf("the recommender target %s %s is not served by the cluster, %v", selector.APIVersion, selector.Kind, err)
+		}
+	}
+
+	for _, namespace := range rule.Spec.NamespaceSelector.MatchNames {
+		if _, err := commonOptions.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("the namespace %s not found", namespace)
+			}
+			return fmt.Errorf("failed to get the namespace %s, %v", namespace, err)
+		}
+	}
+
+	existingRules, err := commonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the recommendation rules, %v", err)
+	}
+	for _, existingRule := range existingRules.Items {
+		if existingRule.Name == rule.Name {
+			continue
+		}
+		if overlapped := overlappedSelectors(rule, &existingRule); len(overlapped) > 0 {
+			klog.Warningf("the recommendation rule %s may select the same %s as the existing recommendation rule %s", rule.Name, strings.Join(overlapped, ", "), existingRule.Name)
+		}
+	}
+
+	return nil
+}
+
+// overlappedSelectors returns the targets possibly selected by both recommendation rules,
+// the label selectors are not compared so the targets with different labels are reported as well.
+func overlappedSelectors(rule, other *v1alpha1.RecommendationRule) []string {
+	if !namespacesOverlapped(rule.Spec.NamespaceSelector, other.Spec.NamespaceSelector) {
+		return nil
+	}
+
+	var overlapped []string
+	for _, selector := range rule.Spec.ResourceSelectors {
+		for _, otherSelector := range other.Spec.ResourceSelectors {
+			if selector.Kind != otherSelector.Kind || selector.APIVersion != otherSelector.APIVersion {
+				continue
+			}
+			if len(selector.Name) > 0 && len(otherSelector.Name) > 0 && selector.Name != otherSelector.Name {
+				continue
+			}
+
+			target := selector.Kind
+			if len(selector.Name) > 0 {
+				target = selector.Kind + "/" + selector.Name
+			} else if len(otherSelector.Name) > 0 {
+				target = otherSelector.Kind + "/" + otherSelector.Name
+			}
+			overlapped = append(overlapped, target)
+			break
+		}
+	}
+
+	return overlapped
+}
+
+func namespacesOverlapped(namespaceSelector, other v1alpha1.NamespaceSelector) bool {
+	if namespaceSelector.Any || other.Any {
+		return true
+	}
+
+	for _, namespace := range namespaceSelector.MatchNames {
+		for _, otherNamespace := range other.MatchNames {
+			if namespace == otherNamespace {
+				return true
+			}
+		}
+	}
+
+	return false
+}
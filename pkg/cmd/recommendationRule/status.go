@@ -0,0 +1,219 @@
+package recommendationRule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/klog/v2"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+)
+
+var (
+	recommendationRuleStatusExample = `
+# view the health of the specified recommendation rule
+%[1]s rr status workloads-rule
+
+# view the failed targets of the specified recommendation rule only
+%[1]s rr status workloads-rule --failed-only
+`
+)
+
+type RecommendationRuleStatusOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Name       string
+	FailedOnly bool
+}
+
+func NewRecommendationRuleStatusOptions() *RecommendationRuleStatusOptions {
+	return &RecommendationRuleStatusOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdRecommendationRuleStatus() *cobra.Command {
+	o := NewRecommendationRuleStatusOptions()
+
+	command := &cobra.Command{
+		Use:     "status <rule>",
+		Short:   "view the status and health of a recommendation rule",
+		Example: fmt.Sprintf(recommendationRuleStatusExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendationRuleStatusExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *RecommendationRuleStatusOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommendation rule name")
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleStatusOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleStatusOptions) Run() error {
+	recommendationRule, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the recommendation rule %s, %v", o.Name, err)
+	}
+
+	filterOptions := recommend.NewRecommendFilterOptions()
+	filterOptions.RuleName = o.Name
+	filterOptions.AllNamespaces = true
+	recommendations, err := filterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	renderStatusSummary(recommendationRule, recommendations, now, o.CommonOptions.Out)
+
+	missions := recommendationRule.Status.Recommendations
+	if o.FailedOnly {
+		missions = nil
+		for _, mission := range recommendationRule.Status.Recommendations {
+			if recommend.MissionStatus(mission) == recommend.MissionStatusFailed {
+				missions = append(missions, mission)
+			}
+		}
+	}
+	if len(missions) > 0 {
+		fmt.Fprintln(o.CommonOptions.Out, "\nTargets:")
+		renderMissions(missions, now, o.CommonOptions.Out)
+	}
+
+	staleRecommendations := recommend.StaleRecommendations(recommendationRule, recommendations, now)
+	if len(staleRecommendations) > 0 {
+		fmt.Fprintln(o.CommonOptions.Out, "\nStale Recommendations:")
+		renderStaleRecommendations(staleRecommendations, now, o.CommonOptions.Out)
+	}
+
+	return nil
+}
+
+func renderStatusSummary(recommendationRule *analysisv1alph1.RecommendationRule, recommendations []analysisv1alph1.Recommendation, now time.Time, out io.Writer) {
+	counts := map[string]int{}
+	for _, mission := range recommendationRule.Status.Recommendations {
+		counts[recommend.MissionStatus(mission)]++
+	}
+
+	lastRun, nextRun := "<none>", "<none>"
+	if recommendationRule.Status.LastUpdateTime != nil {
+		lastUpdateTime := recommendationRule.Status.LastUpdateTime.Time
+		lastRun = fmt.Sprintf("%s (%s ago)", lastUpdateTime.Format(time.RFC3339), duration.HumanDuration(now.Sub(lastUpdateTime)))
+
+		if runInterval, err := time.ParseDuration(recommendationRule.Spec.RunInterval); err == nil {
+			nextUpdateTime := lastUpdateTime.Add(runInterval)
+			if nextUpdateTime.After(now) {
+				nextRun = fmt.Sprintf("%s (in %s)", nextUpdateTime.Format(time.RFC3339), duration.HumanDuration(nextUpdateTime.Sub(now)))
+			} else {
+				nextRun = fmt.Sprintf("%s (overdue by %s)", nextUpdateTime.Format(time.RFC3339), duration.HumanDuration(now.Sub(nextUpdateTime)))
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "%-18s%s\n", "Name:", recommendationRule.Name)
+	fmt.Fprintf(out, "%-18s%s\n", "Run Interval:", recommendationRule.Spec.RunInterval)
+	fmt.Fprintf(out, "%-18s%d\n", "Run Number:", recommendationRule.Status.RunNumber)
+	fmt.Fprintf(out, "%-18s%s\n", "Last Run:", lastRun)
+	fmt.Fprintf(out, "%-18s%s\n", "Next Run:", nextRun)
+	fmt.Fprintf(out, "%-18s%d matched, %d succeeded, %d failed, %d pending\n", "Targets:",
+		len(recommendationRule.Status.Recommendations), counts[recommend.MissionStatusSucceeded], counts[recommend.MissionStatusFailed], counts[recommend.MissionStatusPending])
+	fmt.Fprintf(out, "%-18s%d, %d stale\n", "Recommendations:", len(recommendations), len(recommend.StaleRecommendations(recommendationRule, recommendations, now)))
+}
+
+func renderMissions(missions []analysisv1alph1.RecommendationMission, now time.Time, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"NAMESPACE", "TARGET", "RECOMMENDER", "STATUS", "LAST START", "MESSAGE"})
+
+	for _, mission := range missions {
+		lastStart := "<none>"
+		if mission.LastStartTime != nil {
+			lastStart = duration.HumanDuration(now.Sub(mission.LastStartTime.Time)) + " ago"
+		}
+
+		t.AppendRow(table.Row{
+			mission.TargetRef.Namespace,
+			fmt.Sprintf("%s/%s", mission.TargetRef.Kind, mission.TargetRef.Name),
+			mission.RecommenderRef.Name,
+			recommend.MissionStatus(mission),
+			lastStart,
+			recommend.MissionMessage(mission),
+		})
+	}
+
+	t.Render()
+}
+
+func renderStaleRecommendations(recommendations []analysisv1alph1.Recommendation, now time.Time, out io.Writer) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"NAMESPACE", "NAME", "TARGET", "LAST UPDATE"})
+
+	for _, recommendation := range recommendations {
+		lastUpdate := "never"
+		if recommendation.Status.LastUpdateTime != nil {
+			lastUpdate = duration.HumanDuration(now.Sub(recommendation.Status.LastUpdateTime.Time)) + " ago"
+		}
+
+		t.AppendRow(table.Row{
+			recommendation.Namespace,
+			recommendation.Name,
+			fmt.Sprintf("%s/%s", recommendation.Spec.TargetRef.Kind, recommendation.Spec.TargetRef.Name),
+			lastUpdate,
+		})
+	}
+
+	t.Render()
+}
+
+func (o *RecommendationRuleStatusOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&o.FailedOnly, "failed-only", "", false, "Only show the failed targets")
+}
@@ -0,0 +1,125 @@
+package recommendationRule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+)
+
+var (
+	recommendationRuleTriggerExample = `
+# trigger all the recommendations of the recommendation rule
+%[1]s rr trigger workloads-rule
+
+# pre-commit
+%[1]s rr trigger workloads-rule --dry-run
+
+# trigger the recommendations of the recommendation rule with 50 updates in parallel
+%[1]s rr trigger workloads-rule --concurrency 50
+`
+)
+
+type RecommendationRuleTriggerOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Name        string
+	DryRun      bool
+	Concurrency int
+}
+
+func NewRecommendationRuleTriggerOptions() *RecommendationRuleTriggerOptions {
+	return &RecommendationRuleTriggerOptions{
+		CommonOptions: options.NewCommonOptions(),
+	}
+}
+
+func NewCmdRecommendationRuleTrigger() *cobra.Command {
+	o := NewRecommendationRuleTriggerOptions()
+
+	command := &cobra.Command{
+		Use:     "trigger <rule>",
+		Short:   "trigger all the recommendations of a recommendation rule",
+		Example: fmt.Sprintf(recommendationRuleTriggerExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendationRuleTriggerExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *RecommendationRuleTriggerOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommendation rule name")
+	}
+
+	if o.Concurrency <= 0 {
+		return errors.New("please specify a positive concurrency")
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleTriggerOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleTriggerOptions) Run() error {
+	if _, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Get(context.TODO(), o.Name, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get the recommendation rule %s, %v", o.Name, err)
+	}
+
+	filterOptions := recommend.NewRecommendFilterOptions()
+	filterOptions.RuleName = o.Name
+	filterOptions.AllNamespaces = true
+	recommendations, err := filterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return err
+	}
+
+	if len(recommendations) == 0 {
+		klog.Infof("the recommendation rule %s has no recommendation yet", o.Name)
+		return nil
+	}
+
+	results := recommend.TriggerRecommendations(o.CommonOptions, recommendations, o.DryRun, o.Concurrency)
+	return recommend.SummarizeTriggerResults(results, o.DryRun)
+}
+
+func (o *RecommendationRuleTriggerOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+	cmd.Flags().IntVarP(&o.Concurrency, "concurrency", "", recommend.DefaultTriggerConcurrency, "The number of recommendations triggered in parallel")
+}
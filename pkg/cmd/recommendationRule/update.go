@@ -0,0 +1,205 @@
+package recommendationRule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+)
+
+var (
+	recommendationRuleUpdateExample = `
+# change the run interval of the specified recommendation rule
+%[1]s rr update workloads-rule --run-interval 12h
+
+# change the recommenders and the namespaces of the specified recommendation rule
+%[1]s rr update workloads-rule --recommender Resource,Replicas --namespace default,kube-system
+
+# change the targets only if nobody else changed the rule since resource version 12345
+%[1]s rr update workloads-rule --target '[{"kind": "StatefulSet", "apiVersion": "apps/v1"}]' --resource-version 12345
+
+# pre-commit
+%[1]s rr update workloads-rule --run-interval 12h --dry-run
+`
+)
+
+type RecommendationRuleUpdateOptions struct {
+	CommonOptions *options.CommonOptions
+
+	Name            string
+	Recommender     string
+	Target          string
+	RunInterval     string
+	ResourceVersion string
+	DryRun          bool
+
+	// the flags changed by the user, only these fields are updated
+	changed map[string]bool
+}
+
+func NewRecommendationRuleUpdateOptions() *RecommendationRuleUpdateOptions {
+	return &RecommendationRuleUpdateOptions{
+		CommonOptions: options.NewCommonOptions(),
+		changed:       map[string]bool{},
+	}
+}
+
+func NewCmdRecommendationRuleUpdate() *cobra.Command {
+	o := NewRecommendationRuleUpdateOptions()
+
+	command := &cobra.Command{
+		Use:     "update <rule>",
+		Short:   "update a recommendation rule",
+		Example: fmt.Sprintf(recommendationRuleUpdateExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+recommendationRuleUpdateExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+	o.CommonOptions.AddCommonFlag(command)
+	o.AddFlags(command)
+
+	return command
+}
+
+func (o *RecommendationRuleUpdateOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.Name) == 0 {
+		return errors.New("please specify the recommendation rule name")
+	}
+
+	if !o.changed["recommender"] && !o.changed["target"] && !o.changed["run-interval"] && !o.changed["namespace"] {
+		return errors.New("please specify the fields to update with --recommender, --target, --run-interval or --namespace")
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleUpdateOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Name = args[0]
+	}
+
+	for _, flag := range []string{"recommender", "target", "run-interval", "namespace"} {
+		o.changed[flag] = cmd.Flags().Changed(flag)
+	}
+
+	return nil
+}
+
+func (o *RecommendationRuleUpdateOptions) Run() error {
+	recommendationRule, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Get(context.TODO(), o.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the recommendation rule %s, %v", o.Name, err)
+	}
+	existing := recommendationRule.DeepCopy()
+
+	// the update fails with a conflict if the rule is changed after the resource version
+	if len(o.ResourceVersion) > 0 {
+		recommendationRule.ResourceVersion = o.ResourceVersion
+	}
+
+	if o.changed["recommender"] {
+		recommendationRule.Spec.Recommenders = mergeRecommenders(recommendationRule.Spec.Recommenders, parseRecommenders(o.Recommender))
+	}
+	if o.changed["target"] {
+		var resourceSelectors []v1alpha1.ResourceSelector
+		if err := json.Unmarshal([]byte(o.Target), &resourceSelectors); err != nil {
+			return errors.New("please check the recommender target is valid")
+		}
+		recommendationRule.Spec.ResourceSelectors = resourceSelectors
+	}
+	if o.changed["run-interval"] {
+		recommendationRule.Spec.RunInterval = o.RunInterval
+	}
+	if o.changed["namespace"] {
+		recommendationRule.Spec.NamespaceSelector = parseNamespaceSelector(*o.CommonOptions.ConfigFlags.Namespace)
+	}
+
+	if err := ValidateRecommendationRuleUpdate(existing, recommendationRule); err != nil {
+		return err
+	}
+	if o.changed["target"] || o.changed["namespace"] {
+		if err := ValidateRecommendationRuleInCluster(o.CommonOptions, recommendationRule); err != nil {
+			return err
+		}
+	}
+
+	updateOptions := metav1.UpdateOptions{}
+	if o.DryRun {
+		updateOptions.DryRun = []string{"All"}
+	}
+
+	updated, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().Update(context.TODO(), recommendationRule, updateOptions)
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			return fmt.Errorf("the recommendation rule %s has been modified since it was read, please retry, %v", o.Name, err)
+		}
+		return fmt.Errorf("failed to update the recommendation rule %s, %v", o.Name, err)
+	}
+
+	// when dry-run set, print the object
+	if o.DryRun {
+		updated.Kind = "RecommendationRule"
+		updated.APIVersion = "analysis.crane.io/v1alpha1"
+		printer := printers.NewTypeSetter(scheme.Scheme).ToPrinter(&printers.YAMLPrinter{})
+		if err = printer.PrintObj(updated, o.CommonOptions.Out); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	klog.Infof(fmt.Sprintf("the recommendation rule %s updated successfully", o.Name))
+	return nil
+}
+
+// mergeRecommenders keeps the config of the recommenders still in use
+func mergeRecommenders(existing, recommenders []v1alpha1.Recommender) []v1alpha1.Recommender {
+	for i := range recommenders {
+		for _, recommender := range existing {
+			if recommender.Name == recommenders[i].Name {
+				recommenders[i].Config = recommender.Config
+			}
+		}
+	}
+
+	return recommenders
+}
+
+func (o *RecommendationRuleUpdateOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Recommender, "recommender", "", "", "specify type for recommendationrules，separated with ',' if more than one")
+	cmd.Flags().StringVarP(&o.Target, "target", "", "", "specify recommend target for recommendationrules")
+	cmd.Flags().StringVarP(&o.RunInterval, "run-interval", "", "", "Specify runInterval for recommendationrules")
+	cmd.Flags().StringVarP(&o.ResourceVersion, "resource-version", "", "", "Only update the recommendationrule if its resource version is still the specified one")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "dry-run")
+}
@@ -28,7 +28,13 @@ func NewCmdRecommendationRule() *cobra.Command {
 	recommendationRuleOptions.CommonOptions.AddCommonFlag(cmd)
 
 	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleList())
+	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleGet())
+	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleStatus())
 	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleCreate())
+	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleUpdate())
+	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleEdit())
+	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleDelete())
+	cmd.AddCommand(recommendationRule.NewCmdRecommendationRuleTrigger())
 
 	return cmd
 }
@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	reportExample = `
+# roll up the requested and recommended resources per namespace of the whole cluster
+%[1]s report -A
+
+# roll up the requested and recommended resources per team, the team is read from the label of the workloads
+%[1]s report -A --group-by owner --owner-label team
+
+# the cluster level savings as markdown for the weekly capacity review
+%[1]s report -A --group-by cluster --format markdown
+
+# the rollup per namespace as csv
+%[1]s report -A --format csv > report.csv
+
+# a self-contained html report with charts and the details of each workload for the monthly cost review
+%[1]s report -A --group-by owner --format html > report.html
+
+# the report of a snapshot saved by 'kubectl crane snapshot'
+%[1]s report -A --from-file snapshot.yaml
+`
+)
+
+const (
+	ReportGroupByNamespace = "namespace"
+	ReportGroupByOwner     = "owner"
+	ReportGroupByCluster   = "cluster"
+
+	ReportFormatTable    = "table"
+	ReportFormatCSV      = "csv"
+	ReportFormatJSON     = "json"
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+
+	DefaultOwnerLabel = "team"
+
+	// the group of the workloads without the owner label
+	noneGroup = "<none>"
+	// the group of all the workloads when grouped by cluster
+	clusterGroup = "cluster"
+
+	bytesPerGiB = 1 << 30
+)
+
+// ReportRollup is the sum of the requests and replicas of the workloads in a group
+// before and after adopting the recommendations, the requests and replicas before are
+// the ones of the workloads now, so that the adopted recommendations save nothing more.
+type ReportRollup struct {
+	Name      string `json:"name"`
+	Workloads int    `json:"workloads"`
+
+	CpuCores            float64 `json:"cpuCores"`
+	RecommendedCpuCores float64 `json:"recommendedCpuCores"`
+	CpuSavings          float64 `json:"cpuSavings"`
+	CpuSavingsPercent   float64 `json:"cpuSavingsPercent"`
+
+	MemoryGiB            float64 `json:"memoryGiB"`
+	RecommendedMemoryGiB float64 `json:"recommendedMemoryGiB"`
+	MemorySavings        float64 `json:"memorySavings"`
+	MemorySavingsPercent float64 `json:"memorySavingsPercent"`
+
+	Replicas               int32   `json:"replicas"`
+	RecommendedReplicas    int32   `json:"recommendedReplicas"`
+	ReplicasSavings        int32   `json:"replicasSavings"`
+	ReplicasSavingsPercent float64 `json:"replicasSavingsPercent"`
+
+	// the exact sums the floats above are computed from
+	cpuMilli, recommendedCpuMilli       int64
+	memoryBytes, recommendedMemoryBytes int64
+}
+
+// Add sums the requests and replicas of the workload into the rollup
+func (r *ReportRollup) Add(cost *WorkloadCost) {
+	r.Workloads++
+	r.cpuMilli += cost.Cpu.MilliValue()
+	r.recommendedCpuMilli += cost.RecommendedCpu.MilliValue()
+	r.memoryBytes += cost.Memory.Value()
+	r.recommendedMemoryBytes += cost.RecommendedMemory.Value()
+	r.Replicas += cost.Replicas
+	r.RecommendedReplicas += cost.RecommendedReplicas
+
+	r.CpuCores = roundFloat(float64(r.cpuMilli) / 1000)
+	r.RecommendedCpuCores = roundFloat(float64(r.recommendedCpuMilli) / 1000)
+	r.CpuSavings = roundFloat(float64(r.cpuMilli-r.recommendedCpuMilli) / 1000)
+	r.CpuSavingsPercent = savingsPercent(float64(r.cpuMilli-r.recommendedCpuMilli), float64(r.cpuMilli))
+	r.MemoryGiB = roundFloat(float64(r.memoryBytes) / bytesPerGiB)
+	r.RecommendedMemoryGiB = roundFloat(float64(r.recommendedMemoryBytes) / bytesPerGiB)
+	r.MemorySavings = roundFloat(float64(r.memoryBytes-r.recommendedMemoryBytes) / bytesPerGiB)
+	r.MemorySavingsPercent = savingsPercent(float64(r.memoryBytes-r.recommendedMemoryBytes), float64(r.memoryBytes))
+	r.ReplicasSavings = r.Replicas - r.RecommendedReplicas
+	r.ReplicasSavingsPercent = savingsPercent(float64(r.ReplicasSavings), float64(r.Replicas))
+}
+
+// Report is the rollups of the workloads by the group and the total of the cluster
+type Report struct {
+	GroupBy string         `json:"groupBy"`
+	Groups  []ReportRollup `json:"groups"`
+	Total   ReportRollup   `json:"total"`
+
+	// the workloads the report is built from
+	Workloads []WorkloadCost `json:"-"`
+}
+
+type ReportOptions struct {
+	CommonOptions *options.CommonOptions
+	FilterOptions *recommend.RecommendFilterOptions
+
+	GroupBy    string
+	OwnerLabel string
+	Format     string
+}
+
+func NewReportOptions() *ReportOptions {
+	return &ReportOptions{
+		CommonOptions: options.NewCommonOptions(),
+		FilterOptions: recommend.NewRecommendFilterOptions(),
+	}
+}
+
+func NewCmdReport() *cobra.Command {
+	o := NewReportOptions()
+
+	command := &cobra.Command{
+		Use:     "report",
+		Short:   "Roll up the requested and recommended resources per namespace, owner or cluster",
+		Example: fmt.Sprintf(reportExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+reportExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.AddFlags(command)
+	o.CommonOptions.AddCommonFlag(command)
+	o.CommonOptions.AddFromFileFlag(command)
+
+	return command
+}
+
+func (o *ReportOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	switch o.GroupBy {
+	case ReportGroupByNamespace, ReportGroupByCluster:
+	case ReportGroupByOwner:
+		if len(o.OwnerLabel) == 0 {
+			return fmt.Errorf("please specify the owner label with --owner-label")
+		}
+	default:
+		return fmt.Errorf("unsupported group %s, please specify one of %s, %s, %s", o.GroupBy, ReportGroupByNamespace, ReportGroupByOwner, ReportGroupByCluster)
+	}
+
+	switch o.Format {
+	case ReportFormatTable, ReportFormatCSV, ReportFormatJSON, ReportFormatMarkdown, ReportFormatHTML:
+	default:
+		return fmt.Errorf("unsupported format %s, please specify one of %s, %s, %s, %s, %s", o.Format, ReportFormatTable, ReportFormatCSV, ReportFormatJSON, ReportFormatMarkdown, ReportFormatHTML)
+	}
+
+	return nil
+}
+
+func (o *ReportOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *ReportOptions) Run() error {
+	recommendations, err := o.FilterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return err
+	}
+
+	// the prices don't matter, the report is about the requests and replicas only, which the
+	// estimator reads from the workloads rather than from the last run of the recommendations
+	priceModel := &utils.PriceModel{Default: utils.ResourcePrice{Cpu: utils.DefaultCpuPrice, Memory: utils.DefaultMemoryPrice}}
+	costs, err := NewCostEstimator(o.CommonOptions, priceModel, utils.DefaultNodeTypeLabel).Estimate(recommendations)
+	if err != nil {
+		return err
+	}
+
+	report := BuildReport(costs, o.GroupBy, o.OwnerLabel)
+
+	switch o.Format {
+	case ReportFormatCSV:
+		return renderReportCSV(report, o.CommonOptions.Out)
+	case ReportFormatJSON:
+		return renderReportJSON(report, o.CommonOptions.Out)
+	case ReportFormatHTML:
+		return renderReportHTML(report, o.CommonOptions.Out)
+	case ReportFormatMarkdown:
+		newReportTable(report, o.CommonOptions.Out).RenderMarkdown()
+	default:
+		newReportTable(report, o.CommonOptions.Out).Render()
+	}
+
+	return nil
+}
+
+// BuildReport rolls up the workloads by namespace, by the value of the owner label or all together
+func BuildReport(costs []WorkloadCost, groupBy, ownerLabel string) *Report {
+	report := &Report{
+		GroupBy:   groupBy,
+		Total:     ReportRollup{Name: "Total"},
+		Workloads: costs,
+	}
+
+	index := map[string]int{}
+	for i := range costs {
+		cost := &costs[i]
+
+		var name string
+		switch groupBy {
+		case ReportGroupByOwner:
+			name = cost.Labels[ownerLabel]
+			if len(name) == 0 {
+				name = noneGroup
+			}
+		case ReportGroupByCluster:
+			name = clusterGroup
+		default:
+			name = cost.TargetRef.Namespace
+		}
+
+		if _, exist := index[name]; !exist {
+			index[name] = len(report.Groups)
+			report.Groups = append(report.Groups, ReportRollup{Name: name})
+		}
+		report.Groups[index[name]].Add(cost)
+		report.Total.Add(cost)
+	}
+
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Name < report.Groups[j].Name
+	})
+
+	return report
+}
+
+func newReportTable(report *Report, out io.Writer) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.SetOutputMirror(out)
+	// keep the units like Gi in the footer as they are
+	t.Style().Format.Footer = text.FormatDefault
+	t.AppendHeader(table.Row{reportGroupHeader(report.GroupBy), "WORKLOADS", "CPU", "RECOMMEND CPU", "CPU SAVINGS",
+		"MEMORY", "RECOMMEND MEMORY", "MEMORY SAVINGS", "REPLICAS", "RECOMMEND REPLICAS", "REPLICAS SAVINGS"})
+
+	var columnConfigs []table.ColumnConfig
+	for _, column := range []string{"CPU", "RECOMMEND CPU", "CPU SAVINGS", "MEMORY", "RECOMMEND MEMORY", "MEMORY SAVINGS"} {
+		columnConfigs = append(columnConfigs, table.ColumnConfig{Name: column, Align: text.AlignRight, AlignFooter: text.AlignRight})
+	}
+	t.SetColumnConfigs(columnConfigs)
+
+	for i := range report.Groups {
+		t.AppendRow(reportRow(&report.Groups[i]))
+	}
+	t.AppendFooter(reportRow(&report.Total))
+
+	return t
+}
+
+func reportGroupHeader(groupBy string) string {
+	switch groupBy {
+	case ReportGroupByOwner:
+		return "OWNER"
+	case ReportGroupByCluster:
+		return "CLUSTER"
+	default:
+		return "NAMESPACE"
+	}
+}
+
+func reportRow(rollup *ReportRollup) table.Row {
+	return table.Row{
+		rollup.Name, rollup.Workloads,
+		printCores(rollup.CpuCores), printCores(rollup.RecommendedCpuCores),
+		fmt.Sprintf("%s (%.1f%%)", printCores(rollup.CpuSavings), rollup.CpuSavingsPercent),
+		printGiB(rollup.MemoryGiB), printGiB(rollup.RecommendedMemoryGiB),
+		fmt.Sprintf("%s (%.1f%%)", printGiB(rollup.MemorySavings), rollup.MemorySavingsPercent),
+		rollup.Replicas, rollup.RecommendedReplicas,
+		fmt.Sprintf("%d (%.1f%%)", rollup.ReplicasSavings, rollup.ReplicasSavingsPercent),
+	}
+}
+
+func renderReportCSV(report *Report, out io.Writer) error {
+	w := csv.NewWriter(out)
+	header := []string{report.GroupBy, "workloads", "cpu_cores", "recommended_cpu_cores", "cpu_savings", "cpu_savings_percent",
+		"memory_gib", "recommended_memory_gib", "memory_savings", "memory_savings_percent",
+		"replicas", "recommended_replicas", "replicas_savings", "replicas_savings_percent"}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	rollups := append(append([]ReportRollup{}, report.Groups...), report.Total)
+	for _, rollup := range rollups {
+		record := []string{rollup.Name, strconv.Itoa(rollup.Workloads),
+			formatFloat(rollup.CpuCores), formatFloat(rollup.RecommendedCpuCores), formatFloat(rollup.CpuSavings), formatFloat(rollup.CpuSavingsPercent),
+			formatFloat(rollup.MemoryGiB), formatFloat(rollup.RecommendedMemoryGiB), formatFloat(rollup.MemorySavings), formatFloat(rollup.MemorySavingsPercent),
+			strconv.Itoa(int(rollup.Replicas)), strconv.Itoa(int(rollup.RecommendedReplicas)), strconv.Itoa(int(rollup.ReplicasSavings)), formatFloat(rollup.ReplicasSavingsPercent)}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func renderReportJSON(report *Report, out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(report)
+}
+
+func savingsPercent(savings, current float64) float64 {
+	if current == 0 {
+		return 0
+	}
+
+	return math.Round(savings/current*1000) / 10
+}
+
+// roundFloat rounds the value to 3 decimals
+func roundFloat(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+func printCores(cores float64) string {
+	return fmt.Sprintf("%.2f", cores)
+}
+
+func printGiB(gib float64) string {
+	return fmt.Sprintf("%.2fGi", gib)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 3, 64)
+}
+
+func (o *ReportOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.GroupBy, "group-by", "", ReportGroupByNamespace, "Roll up the workloads by namespace, owner or cluster")
+	cmd.Flags().StringVarP(&o.OwnerLabel, "owner-label", "", DefaultOwnerLabel, "The label of the workloads holding the owner, used when grouped by owner")
+	cmd.Flags().StringVarP(&o.Format, "format", "", ReportFormatTable, "The output format, one of table, csv, json, markdown or html")
+	o.FilterOptions.AddFlags(cmd)
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// htmlChart is a bar chart of the requested and recommended resources of each namespace
+type htmlChart struct {
+	Title  string
+	Height int
+	Bars   []htmlBar
+}
+
+type htmlBar struct {
+	Y     int
+	Label string
+	Value string
+	// the widths in percentage of the largest requested value of the chart
+	CurrentWidth     float64
+	RecommendedWidth float64
+}
+
+type htmlWorkload struct {
+	ID string
+	*WorkloadCost
+	CpuSavingsPercent    float64
+	MemorySavingsPercent float64
+	Containers           []htmlContainer
+}
+
+type htmlContainer struct {
+	Name              string
+	Cpu               string
+	RecommendedCpu    string
+	Memory            string
+	RecommendedMemory string
+}
+
+type htmlReport struct {
+	GeneratedAt string
+	GroupHeader string
+	Groups      []ReportRollup
+	Total       ReportRollup
+	Charts      []htmlChart
+	Workloads   []htmlWorkload
+}
+
+// renderReportHTML writes the report as a single static html file, the tables are sortable by
+// clicking the headers and each workload has a detail page, no network is needed to view it.
+func renderReportHTML(report *Report, out io.Writer) error {
+	data := htmlReport{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		GroupHeader: strings.ToLower(reportGroupHeader(report.GroupBy)),
+		Groups:      report.Groups,
+		Total:       report.Total,
+	}
+
+	namespaces := BuildReport(report.Workloads, ReportGroupByNamespace, "").Groups
+	data.Charts = []htmlChart{
+		newHTMLChart("CPU (cores) requested vs recommended", namespaces, func(rollup *ReportRollup) (float64, float64, string) {
+			return rollup.CpuCores, rollup.RecommendedCpuCores, fmt.Sprintf("%s / %s, %.1f%% over-provisioned", printCores(rollup.CpuCores), printCores(rollup.RecommendedCpuCores), rollup.CpuSavingsPercent)
+		}),
+		newHTMLChart("Memory (GiB) requested vs recommended", namespaces, func(rollup *ReportRollup) (float64, float64, string) {
+			return rollup.MemoryGiB, rollup.RecommendedMemoryGiB, fmt.Sprintf("%s / %s, %.1f%% over-provisioned", printGiB(rollup.MemoryGiB), printGiB(rollup.RecommendedMemoryGiB), rollup.MemorySavingsPercent)
+		}),
+	}
+
+	for i := range report.Workloads {
+		cost := &report.Workloads[i]
+		workload := htmlWorkload{
+			ID:           fmt.Sprintf("workload-%d", i),
+			WorkloadCost: cost,
+		}
+
+		rollup := ReportRollup{}
+		rollup.Add(cost)
+		workload.CpuSavingsPercent = rollup.CpuSavingsPercent
+		workload.MemorySavingsPercent = rollup.MemorySavingsPercent
+
+		for j, container := range cost.Containers {
+			htmlContainer := htmlContainer{
+				Name:   container.Name,
+				Cpu:    PrintQuantity(&container.Cpu),
+				Memory: PrintQuantity(&container.Memory),
+			}
+			if j < len(cost.RecommendedContainers) {
+				htmlContainer.RecommendedCpu = PrintQuantity(&cost.RecommendedContainers[j].Cpu)
+				htmlContainer.RecommendedMemory = PrintQuantity(&cost.RecommendedContainers[j].Memory)
+			}
+			workload.Containers = append(workload.Containers, htmlContainer)
+		}
+
+		data.Workloads = append(data.Workloads, workload)
+	}
+
+	return reportHTMLTemplate.Execute(out, data)
+}
+
+func newHTMLChart(title string, rollups []ReportRollup, values func(rollup *ReportRollup) (float64, float64, string)) htmlChart {
+	const barHeight = 28
+
+	chart := htmlChart{Title: title, Height: len(rollups)*barHeight + 4}
+
+	var max float64
+	for i := range rollups {
+		current, recommended, _ := values(&rollups[i])
+		max = math.Max(max, math.Max(current, recommended))
+	}
+
+	for i := range rollups {
+		current, recommended, value := values(&rollups[i])
+		bar := htmlBar{Y: i * barHeight, Label: rollups[i].Name, Value: value}
+		if max > 0 {
+			bar.CurrentWidth = current / max * 100
+			bar.RecommendedWidth = recommended / max * 100
+		}
+		chart.Bars = append(chart.Bars, bar)
+	}
+
+	return chart
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cores":    printCores,
+	"gib":      printGiB,
+	"quantity": PrintQuantity,
+	"add":      func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crane Recommendation Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.25em; margin-top: 2em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+table { border-collapse: collapse; margin: 1em 0; font-size: .9em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; }
+th { background: #f6f8fa; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tfoot td { font-weight: bold; background: #f6f8fa; }
+.savings { color: #1a7f37; }
+.waste { color: #cf222e; }
+.chart { width: 100%; max-width: 960px; }
+.chart text { font-size: 12px; }
+.legend span { display: inline-block; width: 12px; height: 12px; margin: 0 4px 0 12px; vertical-align: middle; }
+.detail { display: none; }
+.detail:target { display: block; }
+</style>
+</head>
+<body>
+<h1>Crane Recommendation Report</h1>
+<p>Generated at {{ .GeneratedAt }} for {{ .Total.Workloads }} workloads.</p>
+
+<h2 id="summary">Summary by {{ .GroupHeader }}</h2>
+<table class="sortable">
+<thead><tr>
+<th>{{ .GroupHeader }}</th><th>workloads</th><th>cpu</th><th>recommend cpu</th><th>cpu savings</th><th>memory</th><th>recommend memory</th><th>memory savings</th><th>replicas</th><th>recommend replicas</th><th>replicas savings</th>
+</tr></thead>
+<tbody>
+{{- range .Groups }}
+{{ template "rollup" . }}
+{{- end }}
+</tbody>
+<tfoot>{{ template "rollup" .Total }}</tfoot>
+</table>
+
+<h2 id="namespaces">Over-provisioning by namespace</h2>
+<p class="legend"><span style="background:#afb8c1"></span>requested<span style="background:#0969da"></span>recommended</p>
+{{- range .Charts }}
+{{- $height := .Height }}
+<h3>{{ .Title }}</h3>
+<svg class="chart" height="{{ .Height }}" role="img" aria-label="{{ .Title }}">
+{{- range .Bars }}
+<text x="0" y="{{ add .Y 17 }}">{{ .Label }}</text>
+<svg x="20%" width="45%" height="{{ $height }}" overflow="visible">
+<rect x="0" y="{{ add .Y 2 }}" width="{{ printf "%.2f" .CurrentWidth }}%" height="22" fill="#afb8c1"></rect>
+<rect x="0" y="{{ add .Y 7 }}" width="{{ printf "%.2f" .RecommendedWidth }}%" height="12" fill="#0969da"></rect>
+</svg>
+<text x="67%" y="{{ add .Y 17 }}">{{ .Value }}</text>
+{{- end }}
+</svg>
+{{- end }}
+
+<h2 id="workloads">Workloads</h2>
+<table class="sortable">
+<thead><tr>
+<th>namespace</th><th>kind</th><th>name</th><th>rule</th><th>replicas</th><th>recommend replicas</th><th>cpu</th><th>recommend cpu</th><th>cpu savings</th><th>memory</th><th>recommend memory</th><th>memory savings</th>
+</tr></thead>
+<tbody>
+{{- range .Workloads }}
+<tr>
+<td>{{ .TargetRef.Namespace }}</td><td>{{ .TargetRef.Kind }}</td><td><a href="#{{ .ID }}">{{ .TargetRef.Name }}</a></td><td>{{ .Rule }}</td>
+<td class="num">{{ .Replicas }}</td><td class="num">{{ .RecommendedReplicas }}</td>
+<td class="num" data-sort="{{ .Cpu.MilliValue }}">{{ quantity .Cpu }}</td><td class="num" data-sort="{{ .RecommendedCpu.MilliValue }}">{{ quantity .RecommendedCpu }}</td>
+<td class="num {{ if lt .CpuSavingsPercent 0.0 }}waste{{ else }}savings{{ end }}" data-sort="{{ .CpuSavingsPercent }}">{{ printf "%.1f" .CpuSavingsPercent }}%</td>
+<td class="num" data-sort="{{ .Memory.Value }}">{{ quantity .Memory }}</td><td class="num" data-sort="{{ .RecommendedMemory.Value }}">{{ quantity .RecommendedMemory }}</td>
+<td class="num {{ if lt .MemorySavingsPercent 0.0 }}waste{{ else }}savings{{ end }}" data-sort="{{ .MemorySavingsPercent }}">{{ printf "%.1f" .MemorySavingsPercent }}%</td>
+</tr>
+{{- end }}
+</tbody>
+</table>
+
+{{- range .Workloads }}
+<section class="detail" id="{{ .ID }}">
+<h2>{{ .TargetRef.Kind }} {{ .TargetRef.Namespace }}/{{ .TargetRef.Name }}</h2>
+<p><a href="#workloads">&larr; back to workloads</a></p>
+<table>
+<tr><th>api version</th><td>{{ .TargetRef.APIVersion }}</td></tr>
+<tr><th>recommendation rule</th><td>{{ .Rule }}</td></tr>
+<tr><th>replicas</th><td>{{ .Replicas }} &rarr; {{ .RecommendedReplicas }}</td></tr>
+<tr><th>cpu of all replicas</th><td>{{ quantity .Cpu }} &rarr; {{ quantity .RecommendedCpu }} ({{ printf "%.1f" .CpuSavingsPercent }}% savings)</td></tr>
+<tr><th>memory of all replicas</th><td>{{ quantity .Memory }} &rarr; {{ quantity .RecommendedMemory }} ({{ printf "%.1f" .MemorySavingsPercent }}% savings)</td></tr>
+</table>
+{{- if .Containers }}
+<h3>Containers</h3>
+<table class="sortable">
+<thead><tr><th>container</th><th>cpu</th><th>recommend cpu</th><th>memory</th><th>recommend memory</th></tr></thead>
+<tbody>
+{{- range .Containers }}
+<tr><td>{{ .Name }}</td><td class="num">{{ .Cpu }}</td><td class="num">{{ .RecommendedCpu }}</td><td class="num">{{ .Memory }}</td><td class="num">{{ .RecommendedMemory }}</td></tr>
+{{- end }}
+</tbody>
+</table>
+{{- end }}
+</section>
+{{- end }}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("thead th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("asc");
+      table.querySelectorAll("thead th").forEach(function (other) { other.classList.remove("asc", "desc"); });
+      th.classList.add(ascending ? "asc" : "desc");
+
+      var tbody = table.tBodies[0];
+      var value = function (row) {
+        var cell = row.cells[column];
+        var text = cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent.trim();
+        var number = parseFloat(text);
+        return isNaN(number) ? text.toLowerCase() : number;
+      };
+      Array.from(tbody.rows).sort(function (a, b) {
+        var x = value(a), y = value(b);
+        var result = (typeof x === "number" && typeof y === "number") ? x - y : String(x).localeCompare(String(y));
+        return ascending ? result : -result;
+      }).forEach(function (row) { tbody.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+{{ define "rollup" -}}
+<tr>
+<td>{{ .Name }}</td><td class="num">{{ .Workloads }}</td>
+<td class="num">{{ cores .CpuCores }}</td><td class="num">{{ cores .RecommendedCpuCores }}</td>
+<td class="num {{ if lt .CpuSavings 0.0 }}waste{{ else }}savings{{ end }}" data-sort="{{ .CpuSavings }}">{{ cores .CpuSavings }} ({{ printf "%.1f" .CpuSavingsPercent }}%)</td>
+<td class="num">{{ gib .MemoryGiB }}</td><td class="num">{{ gib .RecommendedMemoryGiB }}</td>
+<td class="num {{ if lt .MemorySavings 0.0 }}waste{{ else }}savings{{ end }}" data-sort="{{ .MemorySavings }}">{{ gib .MemorySavings }} ({{ printf "%.1f" .MemorySavingsPercent }}%)</td>
+<td class="num">{{ .Replicas }}</td><td class="num">{{ .RecommendedReplicas }}</td>
+<td class="num" data-sort="{{ .ReplicasSavings }}">{{ .ReplicasSavings }} ({{ printf "%.1f" .ReplicasSavingsPercent }}%)</td>
+</tr>
+{{- end }}
+`))
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
+)
+
+var (
+	snapshotExample = `
+# save the recommendations of the whole cluster with their rules and workloads
+%[1]s snapshot -A --output-file snapshot.yaml
+
+# save the recommendations in kube-system namespace to stdout
+%[1]s snapshot -n kube-system --output-file -
+
+# analyze the snapshot without access to the cluster
+%[1]s recommend list --from-file snapshot.yaml -A
+%[1]s report --from-file snapshot.yaml -A --format markdown
+`
+)
+
+const DefaultSnapshotFile = "snapshot.yaml"
+
+type SnapshotOptions struct {
+	CommonOptions *options.CommonOptions
+	FilterOptions *recommend.RecommendFilterOptions
+
+	OutputFile string
+	NoPods     bool
+}
+
+func NewSnapshotOptions() *SnapshotOptions {
+	return &SnapshotOptions{
+		CommonOptions: options.NewCommonOptions(),
+		FilterOptions: recommend.NewRecommendFilterOptions(),
+	}
+}
+
+func NewCmdSnapshot() *cobra.Command {
+	o := NewSnapshotOptions()
+
+	command := &cobra.Command{
+		Use:     "snapshot",
+		Short:   "Save the recommendations, recommendation rules and workloads to analyze offline with --from-file",
+		Example: fmt.Sprintf(snapshotExample, "kubectl-crane"),
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				klog.Infof(fmt.Sprintf("\nExample:\n"+snapshotExample, "kubectl-crane"))
+				return err
+			}
+
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.AddFlags(command)
+	o.CommonOptions.AddCommonFlag(command)
+
+	return command
+}
+
+func (o *SnapshotOptions) Validate() error {
+	if err := o.CommonOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.FilterOptions.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.OutputFile) == 0 {
+		return fmt.Errorf("please specify the snapshot file with --output-file")
+	}
+
+	return nil
+}
+
+func (o *SnapshotOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.CommonOptions.Complete(cmd, args); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *SnapshotOptions) Run() error {
+	objects, err := o.collect()
+	if err != nil {
+		return err
+	}
+
+	out := o.CommonOptions.Out
+	if o.OutputFile != "-" {
+		file, err := os.Create(o.OutputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := writeSnapshot(objects, out); err != nil {
+		return err
+	}
+
+	if o.OutputFile != "-" {
+		klog.Infof("saved %d objects to %s", len(objects), o.OutputFile)
+	}
+	return nil
+}
+
+// collect reads the recommendations, all the recommendation rules, the targets of the recommendations,
+// their pods and the nodes, which are what the offline commands read.
+func (o *SnapshotOptions) collect() ([]runtime.Object, error) {
+	var objects []runtime.Object
+
+	recommendations, err := o.FilterOptions.ListRecommendations(o.CommonOptions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range recommendations {
+		recommendations[i].SetGroupVersionKind(analysisv1alph1.SchemeGroupVersion.WithKind("Recommendation"))
+		objects = append(objects, &recommendations[i])
+	}
+
+	rules, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().RecommendationRules().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules.Items {
+		rules.Items[i].SetGroupVersionKind(analysisv1alph1.SchemeGroupVersion.WithKind("RecommendationRule"))
+		objects = append(objects, &rules.Items[i])
+	}
+
+	seen := map[string]bool{}
+	namespaces := map[string]bool{}
+	for _, recommendation := range recommendations {
+		targetRef := recommendation.Spec.TargetRef
+		key := GetObjectRefKey("", targetRef)
+		if seen[key] || len(targetRef.Name) == 0 {
+			continue
+		}
+		seen[key] = true
+
+		target, err := o.getTarget(targetRef)
+		if err != nil {
+			klog.Warningf("Failed to get %s %s/%s, %v.", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+			continue
+		}
+		objects = append(objects, target)
+		if len(targetRef.Namespace) > 0 {
+			namespaces[targetRef.Namespace] = true
+		}
+
+		if o.NoPods {
+			continue
+		}
+		pods, err := o.listPods(target)
+		if err != nil {
+			klog.Warningf("Failed to list the pods of %s %s/%s, %v.", targetRef.Kind, targetRef.Namespace, targetRef.Name, err)
+			continue
+		}
+		objects = append(objects, pods...)
+	}
+
+	var namespaceNames []string
+	for namespace := range namespaces {
+		namespaceNames = append(namespaceNames, namespace)
+	}
+	sort.Strings(namespaceNames)
+	for _, name := range namespaceNames {
+		namespace, err := o.CommonOptions.KubeClient.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			klog.Warningf("Failed to get the namespace %s, %v.", name, err)
+			continue
+		}
+		namespace.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+		objects = append(objects, namespace)
+	}
+
+	if !o.NoPods {
+		nodes, err := o.CommonOptions.KubeClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range nodes.Items {
+			nodes.Items[i].SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+			objects = append(objects, &nodes.Items[i])
+		}
+	}
+
+	return objects, nil
+}
+
+func (o *SnapshotOptions) getTarget(targetRef corev1.ObjectReference) (*unstructured.Unstructured, error) {
+	gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, targetRef.APIVersion, targetRef.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(targetRef.Namespace).Get(context.TODO(), targetRef.Name, metav1.GetOptions{})
+}
+
+// listPods lists the pods selected by the workload, the node they run on tells the node type
+func (o *SnapshotOptions) listPods(target *unstructured.Unstructured) ([]runtime.Object, error) {
+	matchLabels, _, err := unstructured.NestedStringMap(target.Object, "spec", "selector", "matchLabels")
+	if err != nil || len(matchLabels) == 0 {
+		return nil, err
+	}
+
+	podList, err := o.CommonOptions.KubeClient.CoreV1().Pods(target.GetNamespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(matchLabels).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []runtime.Object
+	for i := range podList.Items {
+		podList.Items[i].SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		pods = append(pods, &podList.Items[i])
+	}
+
+	return pods, nil
+}
+
+// writeSnapshot writes the objects as yaml documents without the managed fields
+func writeSnapshot(objects []runtime.Object, out io.Writer) error {
+	for _, object := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			return err
+		}
+		unstructured.RemoveNestedField(content, "metadata", "managedFields")
+
+		data, err := yaml.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *SnapshotOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.OutputFile, "output-file", "", DefaultSnapshotFile, "The file to save the snapshot to, '-' writes to stdout")
+	cmd.Flags().BoolVarP(&o.NoPods, "no-pods", "", false, "Don't save the pods and nodes, the node types are unknown when estimating the cost from the snapshot")
+	o.FilterOptions.AddFlags(cmd)
+}
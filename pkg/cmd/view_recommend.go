@@ -20,11 +20,15 @@ var (
 	viewRecommendExample = `
 # view all recommend result with kube-system namespace
 %[1]s view-recommend --api-version apps/v1 --kind Deployment -n {namespace} {name}
+
+# view the recommend result of a workload saved by 'kubectl crane snapshot'
+%[1]s view-recommend --from-file snapshot.yaml --api-version apps/v1 --kind Deployment -n {namespace} {name}
 `
 )
 
 type ViewRecommendOptions struct {
 	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
 
 	APIVersion string
 	Kind       string
@@ -35,6 +39,7 @@ type ViewRecommendOptions struct {
 func NewViewRecommendOptions() *ViewRecommendOptions {
 	return &ViewRecommendOptions{
 		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
 	}
 }
 
@@ -62,6 +67,8 @@ func NewCmdViewRecommend() *cobra.Command {
 		},
 	}
 	o.CommonOptions.AddCommonFlag(command)
+	o.CommonOptions.AddFromFileFlag(command)
+	o.PrintOptions.AddPrintFlags(command)
 	o.AddFlags(command)
 
 	return command
@@ -72,6 +79,10 @@ func (o *ViewRecommendOptions) Validate(args []string) error {
 		return err
 	}
 
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
 	if o.APIVersion == "" || o.Kind == "" || o.CommonOptions.ConfigFlags.Namespace == nil || len(args) == 0 {
 		return errors.New("the recommender target is valid, please follow the guide `kubectl-crane view-recommend --api-version apps/v1 --kind Deployment -n {namespace} {name}`")
 	}
@@ -113,9 +124,7 @@ func (o *ViewRecommendOptions) Run() error {
 		}
 	}
 
-	recommend.RenderTable(recommendations, o.CommonOptions.Out)
-
-	return nil
+	return recommend.PrintRecommendations(recommendations, o.PrintOptions, recommend.NewHealthChecker(o.CommonOptions, o.PrintOptions.IsWide()), o.CommonOptions.Out)
 }
 
 func (o *ViewRecommendOptions) AddFlags(cmd *cobra.Command) {
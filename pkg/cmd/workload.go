@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
@@ -10,11 +9,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 
 	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
 
 	"github.com/gocrane/kubectl-crane/pkg/cmd/options"
+	"github.com/gocrane/kubectl-crane/pkg/cmd/recommend"
+	"github.com/gocrane/kubectl-crane/pkg/utils"
 )
 
 type WorkloadMeta struct {
@@ -22,14 +25,24 @@ type WorkloadMeta struct {
 	Kind       string
 }
 
+// builtinWorkloadMetas are the workloads always listed, the other scalable
+// workloads are listed when there are recommendations targeting them.
+var builtinWorkloadMetas = []WorkloadMeta{
+	{ApiVersion: "apps/v1", Kind: "Deployment"},
+	{ApiVersion: "apps/v1", Kind: "StatefulSet"},
+	{ApiVersion: "apps/v1", Kind: "DaemonSet"},
+}
+
 type CraneWorkloadOptions struct {
 	CommonOptions *options.CommonOptions
+	PrintOptions  *options.PrintOptions
 	AllNamespaces bool
 }
 
 func NewCraneWorkloadOptions() *CraneWorkloadOptions {
 	return &CraneWorkloadOptions{
 		CommonOptions: options.NewCommonOptions(),
+		PrintOptions:  options.NewPrintOptions(),
 	}
 }
 
@@ -57,6 +70,7 @@ func NewCmdCraneWorkload() *cobra.Command {
 
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", o.AllNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
 	o.CommonOptions.AddCommonFlag(cmd)
+	o.PrintOptions.AddPrintFlags(cmd)
 
 	return cmd
 }
@@ -66,6 +80,10 @@ func (o *CraneWorkloadOptions) Validate() error {
 		return err
 	}
 
+	if err := o.PrintOptions.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -87,47 +105,54 @@ func (o *CraneWorkloadOptions) Run() error {
 		namespace = ""
 	}
 
-	deploymentList, err := o.CommonOptions.KubeClient.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		klog.Errorf("Failed to get deployments, %v.", err)
-		return err
-	}
-
-	/*	statefulsetList, err := kubeClient.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			klog.Errorf("Failed to get statefulsets, %v.", err)
-			return err
-		}*/
-
-	recommendList, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations("").List(context.TODO(), metav1.ListOptions{})
+	recommendList, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Recommendations(namespace).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		klog.Errorf("Failed to get recommends, %v.", err)
 		return err
 	}
 
 	recommendMap := map[string]analysisv1alph1.Recommendation{}
+	workloadMetas := append([]WorkloadMeta{}, builtinWorkloadMetas...)
 	for _, recommend := range recommendList.Items {
 		recommendMap[GetObjectRefKey(string(recommend.Spec.Type), recommend.Spec.TargetRef)] = recommend
+
+		// the scalable CRDs are found by the recommendations targeting them
+		if recommend.Spec.Type != analysisv1alph1.AnalysisTypeResource && recommend.Spec.Type != analysisv1alph1.AnalysisTypeReplicas {
+			continue
+		}
+		meta := WorkloadMeta{ApiVersion: recommend.Spec.TargetRef.APIVersion, Kind: recommend.Spec.TargetRef.Kind}
+		if !containsWorkloadMeta(workloadMetas, meta) {
+			workloadMetas = append(workloadMetas, meta)
+		}
 	}
 
-	analyticsList, err := o.CommonOptions.CraneClient.AnalysisV1alpha1().Analytics("").List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		klog.Errorf("Failed to get analytics, %v.", err)
-		return err
+	var workloads []unstructured.Unstructured
+	for _, meta := range workloadMetas {
+		gvr, err := utils.GetGroupVersionResource(o.CommonOptions.DiscoveryClient, meta.ApiVersion, meta.Kind)
+		if err != nil {
+			klog.Warningf("Failed to get the resource of %s %s, %v.", meta.ApiVersion, meta.Kind, err)
+			continue
+		}
+
+		workloadList, err := o.CommonOptions.DynamicClient.Resource(*gvr).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			klog.Errorf("Failed to get %s, %v.", gvr.Resource, err)
+			return err
+		}
+		workloads = append(workloads, workloadList.Items...)
 	}
 
-	var workloadMetas []WorkloadMeta
-	for _, analytics := range analyticsList.Items {
-		for _, selector := range analytics.Spec.ResourceSelectors {
-			if strings.ToLower(selector.Kind) != "deployment" &&
-				strings.ToLower(selector.Kind) != "statefulSet" &&
-				strings.ToLower(selector.Kind) != "ReplicaSet" {
-				workloadMetas = append(workloadMetas, WorkloadMeta{
-					Kind:       selector.Kind,
-					ApiVersion: selector.APIVersion,
-				})
+	if !o.PrintOptions.IsTable() {
+		var recommendations []analysisv1alph1.Recommendation
+		for _, workload := range workloads {
+			for _, recType := range []string{analysisv1alph1.ResourceRecommender, analysisv1alph1.ReplicasRecommender} {
+				if recommendation, exist := recommendMap[GetObjectKey(recType, workload.GetKind(), workload.GetAPIVersion(), workload.GetNamespace(), workload.GetName())]; exist {
+					recommendations = append(recommendations, recommendation)
+				}
 			}
 		}
+
+		return recommend.PrintRecommendations(recommendations, o.PrintOptions, recommend.NewHealthChecker(o.CommonOptions, o.PrintOptions.IsWide()), o.CommonOptions.Out)
 	}
 
 	t := table.NewWriter()
@@ -138,6 +163,9 @@ func (o *CraneWorkloadOptions) Run() error {
 		header = append(header, "NAMESPACE")
 	}
 	header = append(header, table.Row{"NAME", "CONTAINER", "TYPE", "CPU", "MEMORY", "RECOMMEND CPU", "RECOMMEND MEMORY", "CPU DIFF", "MEMORY DIFF", "REPLICAS", "RECOMMEND REPLICAS", "REPLICAS DIFF"}...)
+	if o.PrintOptions.IsWide() {
+		header = append(header, "API VERSION")
+	}
 	t.AppendHeader(header)
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{
@@ -166,82 +194,63 @@ func (o *CraneWorkloadOptions) Run() error {
 	diffCpuTotal := resource.NewQuantity(0, resource.DecimalSI)
 	diffMemoryTotal := resource.NewQuantity(0, resource.BinarySI)
 
-	for _, deployment := range deploymentList.Items {
-		proposedRecommendation := GetProposedRecommendationsByMeta("Deployment", "apps/v1", deployment.Namespace, deployment.Name, recommendMap)
+	for _, workload := range workloads {
+		templateMap, found, err := unstructured.NestedMap(workload.Object, "spec", "template")
+		if err != nil || !found {
+			// not a workload managing pods
+			continue
+		}
+		var template corev1.PodTemplateSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(templateMap, &template); err != nil {
+			klog.Warningf("Failed to decode the pod template of %s %s/%s, %v.", workload.GetKind(), workload.GetNamespace(), workload.GetName(), err)
+			continue
+		}
 
-		for _, container := range deployment.Spec.Template.Spec.Containers {
+		proposedRecommendation := GetProposedRecommendationsByMeta(workload.GetKind(), workload.GetAPIVersion(), workload.GetNamespace(), workload.GetName(), recommendMap)
+
+		replicas := utils.WorkloadReplicas(&workload)
+		var recReplicas, replicasDiff int32
+		if proposedRecommendation.ReplicasRecommendation != nil && proposedRecommendation.ReplicasRecommendation.Replicas != nil {
+			recReplicas = *proposedRecommendation.ReplicasRecommendation.Replicas
+			replicasDiff = replicas - recReplicas
+		}
+
+		for _, container := range template.Spec.Containers {
 			row := table.Row{}
 			if o.AllNamespaces {
-				row = append(row, deployment.Namespace)
+				row = append(row, workload.GetNamespace())
 			}
-			row = append(row, deployment.Name)
+			row = append(row, workload.GetName())
 			row = append(row, container.Name)
-			row = append(row, "Deployment")
-
-			containerCpu := resource.NewQuantity(0, resource.DecimalSI)
-			containerMemory := resource.NewQuantity(0, resource.BinarySI)
-			if container.Resources.Requests != nil {
-				requestCpu := container.Resources.Requests[corev1.ResourceCPU]
-				if !requestCpu.IsZero() {
-					containerCpu = &requestCpu
-				}
-				requestMemory := container.Resources.Requests[corev1.ResourceMemory]
-				if !requestMemory.IsZero() {
-					containerMemory = &requestMemory
-				}
-			}
+			row = append(row, workload.GetKind())
+
+			containerCpu, containerMemory := ContainerRequests(container)
 			row = append(row, PrintQuantity(containerCpu))
 			row = append(row, PrintQuantity(containerMemory))
 			cpuTotal.Add(*containerCpu)
 			memoryTotal.Add(*containerMemory)
 
-			recCpu := resource.NewQuantity(0, resource.DecimalSI)
-			recMemory := resource.NewQuantity(0, resource.BinarySI)
-			if proposedRecommendation != nil && proposedRecommendation.ResourceRequest != nil {
-				for _, recContainer := range proposedRecommendation.ResourceRequest.Containers {
-					if recContainer.ContainerName == container.Name {
-						recCpuResource, err := resource.ParseQuantity(recContainer.Target[corev1.ResourceCPU])
-						if err == nil {
-							recCpu = &recCpuResource
-						}
-						recMemoryResource, err := resource.ParseQuantity(recContainer.Target[corev1.ResourceMemory])
-						if err == nil {
-							recMemory = &recMemoryResource
-						}
-					}
-				}
-			}
+			recCpu, recMemory := RecommendedContainerRequests(container.Name, proposedRecommendation.ResourceRequest)
 			row = append(row, PrintQuantity(recCpu))
 			row = append(row, PrintQuantity(recMemory))
 			recCpuTotal.Add(*recCpu)
 			recMemoryTotal.Add(*recMemory)
 
-			containerCpuDiff := resource.NewQuantity(0, resource.DecimalSI)
-			if !recCpu.IsZero() {
-				containerCpuDiff = containerCpu
-				containerCpuDiff.Sub(*recCpu)
-			}
-
-			containerMemoryDiff := resource.NewQuantity(0, resource.BinarySI)
-			if !recMemory.IsZero() {
-				containerMemoryDiff = containerMemory
-				containerMemoryDiff.Sub(*recMemory)
-			}
-
+			containerCpuDiff := QuantityDiff(containerCpu, recCpu)
+			containerMemoryDiff := QuantityDiff(containerMemory, recMemory)
 			row = append(row, PrintQuantity(containerCpuDiff))
 			row = append(row, PrintQuantity(containerMemoryDiff))
 			diffCpuTotal.Add(*containerCpuDiff)
 			diffMemoryTotal.Add(*containerMemoryDiff)
 
-			row = append(row, *deployment.Spec.Replicas)
-			var recReplicas, replicasDiff int32
-			if proposedRecommendation.ReplicasRecommendation != nil {
-				recReplicas = *proposedRecommendation.ReplicasRecommendation.Replicas
-				replicasDiff = *deployment.Spec.Replicas - recReplicas
-			}
+			row = append(row, replicas)
 			row = append(row, PrintReplicas(recReplicas))
 			row = append(row, PrintReplicas(replicasDiff))
 
+			if o.PrintOptions.IsWide() {
+				row = append(row, workload.GetAPIVersion())
+			}
+
 			t.AppendRows([]table.Row{
 				row,
 			})
@@ -250,8 +259,23 @@ func (o *CraneWorkloadOptions) Run() error {
 		t.AppendSeparator()
 	}
 
-	t.AppendFooter(table.Row{"Total", "", "", PrintQuantity(cpuTotal), PrintQuantity(memoryTotal), PrintQuantity(recCpuTotal), PrintQuantity(recMemoryTotal), PrintQuantity(diffCpuTotal), PrintQuantity(diffMemoryTotal)})
+	footer := table.Row{"Total"}
+	if o.AllNamespaces {
+		footer = append(footer, "")
+	}
+	footer = append(footer, table.Row{"", "", PrintQuantity(cpuTotal), PrintQuantity(memoryTotal), PrintQuantity(recCpuTotal), PrintQuantity(recMemoryTotal), PrintQuantity(diffCpuTotal), PrintQuantity(diffMemoryTotal)}...)
+	t.AppendFooter(footer)
 	t.Render()
 
 	return nil
 }
+
+func containsWorkloadMeta(metas []WorkloadMeta, meta WorkloadMeta) bool {
+	for _, m := range metas {
+		if m == meta {
+			return true
+		}
+	}
+
+	return false
+}
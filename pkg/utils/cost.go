@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// HoursPerMonth is the average number of hours in a month
+	HoursPerMonth = 730
+
+	// DefaultCpuPrice and DefaultMemoryPrice are the on-demand prices of one vCPU-hour
+	// and one GiB-hour commonly used when the real prices of the cluster are unknown.
+	DefaultCpuPrice    = 0.031611
+	DefaultMemoryPrice = 0.004237
+
+	// DefaultNodeTypeLabel is the well-known label of nodes holding the instance type
+	DefaultNodeTypeLabel = "node.kubernetes.io/instance-type"
+)
+
+// ResourcePrice is the hourly price of one vCPU and one GiB of memory
+type ResourcePrice struct {
+	Cpu    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+}
+
+// MonthlyCost returns the cost of requesting the cpu and memory for a month
+func (p ResourcePrice) MonthlyCost(cpu, memory resource.Quantity) float64 {
+	cores := float64(cpu.MilliValue()) / 1000
+	gib := float64(memory.Value()) / (1 << 30)
+
+	return (cores*p.Cpu + gib*p.Memory) * HoursPerMonth
+}
+
+// PriceModel prices the resources with a default price and optional prices per node type
+type PriceModel struct {
+	Default   ResourcePrice
+	NodeTypes map[string]ResourcePrice
+}
+
+// PriceOf returns the price of the node type, or the default price if the node type is unknown
+func (m *PriceModel) PriceOf(nodeType string) ResourcePrice {
+	if price, exist := m.NodeTypes[nodeType]; exist {
+		return price
+	}
+
+	return m.Default
+}
+
+// LoadNodeTypePrices loads the prices per node type from a yaml file, e.g.
+//
+//	m5.large:
+//	  cpu: 0.048
+//	  memory: 0.006
+func LoadNodeTypePrices(file string) (map[string]ResourcePrice, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]ResourcePrice{}
+	if err := yaml.UnmarshalStrict(content, &prices); err != nil {
+		return nil, fmt.Errorf("invalid node type prices in %s, %v", file, err)
+	}
+
+	for nodeType, price := range prices {
+		if price.Cpu < 0 || price.Memory < 0 {
+			return nil, fmt.Errorf("invalid prices of node type %s in %s, the prices should not be negative", nodeType, file)
+		}
+	}
+
+	return prices, nil
+}
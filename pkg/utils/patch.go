@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	analysisv1alpha1 "github.com/gocrane/api/analysis/v1alpha1"
+)
+
+// ContainerRequest is the cpu and memory request of one container
+// decoded from the CurrentInfo or RecommendedInfo of a recommendation.
+type ContainerRequest struct {
+	Name   string
+	Cpu    resource.Quantity
+	Memory resource.Quantity
+}
+
+// DecodeResourceInfo decodes a PatchResource json into container requests.
+func DecodeResourceInfo(info string) ([]ContainerRequest, error) {
+	var patchResource analysisv1alpha1.PatchResource
+	if err := json.Unmarshal([]byte(info), &patchResource); err != nil {
+		return nil, err
+	}
+
+	var requests []ContainerRequest
+	for _, container := range patchResource.Spec.Template.Spec.Containers {
+		requests = append(requests, ContainerRequest{
+			Name:   container.Name,
+			Cpu:    *container.Resources.Requests.Cpu(),
+			Memory: *container.Resources.Requests.Memory(),
+		})
+	}
+
+	return requests, nil
+}
+
+// DecodeReplicasInfo decodes a PatchReplicas json into the replicas it carries.
+func DecodeReplicasInfo(info string) (int32, error) {
+	var patchReplicas analysisv1alpha1.PatchReplicas
+	if err := json.Unmarshal([]byte(info), &patchReplicas); err != nil {
+		return 0, err
+	}
+
+	if patchReplicas.Spec.Replicas == nil {
+		return 0, errors.New("replicas not found")
+	}
+
+	return *patchReplicas.Spec.Replicas, nil
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+)
+
+// RowPrinter prints the rows of a table one by one as they come, like kubectl get --watch.
+// The header is printed before the first row and the multi-line cells are joined by ",",
+// the columns are padded to the widest cell printed so far.
+type RowPrinter struct {
+	writer        io.Writer
+	header        table.Row
+	headerPrinted bool
+	widths        []int
+}
+
+func NewRowPrinter(out io.Writer, header table.Row) *RowPrinter {
+	return &RowPrinter{
+		writer: out,
+		header: header,
+	}
+}
+
+func (p *RowPrinter) PrintRow(row table.Row) error {
+	if !p.headerPrinted {
+		if _, err := fmt.Fprintln(p.writer, p.formatRow(p.header)); err != nil {
+			return err
+		}
+		p.headerPrinted = true
+	}
+
+	_, err := fmt.Fprintln(p.writer, p.formatRow(row))
+	return err
+}
+
+func (p *RowPrinter) formatRow(row table.Row) string {
+	var cells []string
+	for i, cell := range row {
+		value := strings.TrimSpace(fmt.Sprint(cell))
+		if len(value) == 0 {
+			value = "<none>"
+		}
+		value = strings.ReplaceAll(value, "\n", ",")
+
+		if i >= len(p.widths) {
+			p.widths = append(p.widths, 0)
+		}
+		if len(value) > p.widths[i] {
+			p.widths[i] = len(value)
+		}
+		if i < len(row)-1 {
+			value += strings.Repeat(" ", p.widths[i]-len(value)+3)
+		}
+		cells = append(cells, value)
+	}
+
+	return strings.Join(cells, "")
+}
+
+// WatchUntilInterrupted runs an informer on the objects of the ListerWatcher and calls
+// onEvent with the event type for the initial objects and every change after that,
+// until the context is done or the process is interrupted.
+func WatchUntilInterrupted(ctx context.Context, lw cache.ListerWatcher, objType runtime.Object, onEvent func(eventType string, obj interface{})) {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_, informer := cache.NewInformer(lw, objType, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			onEvent(WatchEventAdded, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			onEvent(WatchEventModified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			onEvent(WatchEventDeleted, obj)
+		},
+	})
+
+	informer.Run(ctx.Done())
+}
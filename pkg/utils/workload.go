@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WorkloadRequests sums the cpu and memory requests of the containers in the pod template of a workload
+func WorkloadRequests(live *unstructured.Unstructured) (resource.Quantity, resource.Quantity, error) {
+	var cpu, memory resource.Quantity
+
+	containers, _, err := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+	if err != nil {
+		return cpu, memory, err
+	}
+	for _, container := range containers {
+		containerMap, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		requests, _, _ := unstructured.NestedStringMap(containerMap, "resources", "requests")
+		if value, exist := requests[string(corev1.ResourceCPU)]; exist {
+			if quantity, err := resource.ParseQuantity(value); err == nil {
+				cpu.Add(quantity)
+			}
+		}
+		if value, exist := requests[string(corev1.ResourceMemory)]; exist {
+			if quantity, err := resource.ParseQuantity(value); err == nil {
+				memory.Add(quantity)
+			}
+		}
+	}
+
+	return cpu, memory, nil
+}
+
+// WorkloadReplicas returns the replicas of a scalable workload, or the desired number of pods of a DaemonSet
+func WorkloadReplicas(live *unstructured.Unstructured) int32 {
+	if replicas, found, err := unstructured.NestedInt64(live.Object, "spec", "replicas"); err == nil && found {
+		return int32(replicas)
+	}
+
+	if desired, found, err := unstructured.NestedInt64(live.Object, "status", "desiredNumberScheduled"); err == nil && found {
+		return int32(desired)
+	}
+
+	return 1
+}
+
+// WorkloadContainers decodes the containers in the pod template of a workload
+func WorkloadContainers(live *unstructured.Unstructured) ([]corev1.Container, error) {
+	containers, found, err := unstructured.NestedSlice(live.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return nil, err
+	}
+
+	content, err := json.Marshal(containers)
+	if err != nil {
+		return nil, err
+	}
+	var typed []corev1.Container
+	if err := json.Unmarshal(content, &typed); err != nil {
+		return nil, err
+	}
+
+	return typed, nil
+}
+
+// WorkloadContainerRequests returns the cpu and memory requests of each container in the pod template of a workload
+func WorkloadContainerRequests(live *unstructured.Unstructured) ([]ContainerRequest, error) {
+	containers, err := WorkloadContainers(live)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []ContainerRequest
+	for _, container := range containers {
+		requests = append(requests, ContainerRequest{
+			Name:   container.Name,
+			Cpu:    *container.Resources.Requests.Cpu(),
+			Memory: *container.Resources.Requests.Memory(),
+		})
+	}
+
+	return requests, nil
+}